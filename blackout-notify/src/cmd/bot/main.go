@@ -4,13 +4,19 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/yourusername/haaddon/telegram-bot/internal/audit"
 	"github.com/yourusername/haaddon/telegram-bot/internal/bot"
 	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/heartbeat"
 	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
 	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
 	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+	"github.com/yourusername/haaddon/telegram-bot/internal/outages"
+	"github.com/yourusername/haaddon/telegram-bot/internal/sensors"
 	"github.com/yourusername/haaddon/telegram-bot/internal/watcher"
 )
 
@@ -23,6 +29,12 @@ func main() {
 
 	// Initialize logger with configured level
 	logger.SetLevel(cfg.LogLevel)
+
+	if cfg.IsReceiverMode() {
+		runReceiver(cfg)
+		return
+	}
+
 	logger.Info("Starting Telegram Bot for Home Assistant")
 	logger.Debug("Config loaded: HA URL=%s, Polling=%ds", cfg.HAApiURL, cfg.PollingInterval)
 
@@ -32,52 +44,90 @@ func main() {
 
 	// Initialize Home Assistant REST client
 	haClient := homeassistant.NewClient(cfg.HAApiURL, cfg.HAToken)
+	retryPolicy := homeassistant.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = cfg.HARetryAttempts
+	haClient.SetRetryPolicy(retryPolicy)
 
-	// Check connection to Home Assistant
+	// Check connection to Home Assistant. In remote mode HA being unreachable
+	// may itself mean a blackout, so the add-on keeps running.
 	if err := haClient.CheckConnection(ctx); err != nil {
-		logger.Fatal("Failed to connect to Home Assistant: %v", err)
+		if !cfg.InferPowerFromConnection {
+			logger.Fatal("Failed to connect to Home Assistant: %v", err)
+		}
+		logger.Warn("Home Assistant is unreachable, waiting for connection: %v", err)
+	} else {
+		logger.Info("Successfully connected to Home Assistant")
+	}
+
+	// Initialize WebSocket client for real-time events and registry lookups;
+	// the power watcher keeps it connected, or main does for bot commands alone
+	wsClient := homeassistant.NewWSClient(cfg.HAApiURL, cfg.HAToken)
+
+	// Initialize entity state cache shared by the bot and notifications
+	stateCache := homeassistant.NewStateCache(haClient, wsClient, time.Duration(cfg.PollingInterval)*time.Second)
+	stateCache.Track(cfg.PauseEntityID, cfg.NextOnSensorID, cfg.NextOffSensorID)
+	if err := stateCache.Refresh(ctx); err != nil {
+		logger.Debug("Failed to fill state cache: %v", err)
 	}
-	logger.Info("Successfully connected to Home Assistant")
 
 	// Initialize Telegram bot
-	telegramBot, err := bot.New(cfg, haClient)
+	telegramBot, err := bot.New(cfg, haClient, wsClient, stateCache)
 	if err != nil {
 		logger.Fatal("Failed to create Telegram bot: %v", err)
 	}
 
+	// Record bot commands (falls back to in-memory if the data directory is unusable)
+	auditLog, err := audit.New(filepath.Join(cfg.DataDir, "audit.log"))
+	if err != nil {
+		logger.Warn("Failed to open audit log, keeping it in memory: %v", err)
+		auditLog, _ = audit.New("")
+	}
+	telegramBot.SetAuditLog(auditLog)
+
+	// Ping the remote heartbeat receiver, if configured
+	if cfg.IsHeartbeatEnabled() {
+		sender := heartbeat.NewSender(cfg.HeartbeatURL, cfg.HeartbeatToken, time.Duration(cfg.HeartbeatInterval)*time.Second)
+		go sender.Run(ctx)
+	}
+
 	// Handle OS signals for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start Telegram bot command handler if enabled
-	if cfg.IsBotCommandsEnabled() {
-		logger.Info("Bot commands enabled for %d chat(s)", len(cfg.AllowedChatIDs))
-		go func() {
-			if err := telegramBot.Start(ctx); err != nil {
-				logger.Error("Bot error: %v", err)
-				cancel()
-			}
-		}()
-	} else {
-		logger.Info("Bot commands disabled (allowed_chat_ids is empty)")
-	}
-
 	// Initialize power monitoring if configured
 	var powerWatcher *watcher.Watcher
 	if cfg.IsPowerMonitoringEnabled() {
-		logger.Info("Power monitoring enabled for entity: %s", cfg.WatchedEntityID)
-
-		// Initialize WebSocket client for real-time events
-		wsClient := homeassistant.NewWSClient(cfg.HAApiURL, cfg.HAToken)
+		if cfg.WatchedEntityID != "" {
+			logger.Info("Power monitoring enabled for entity: %s", cfg.WatchedEntityID)
+		} else {
+			logger.Info("Power monitoring enabled, inferred from connectivity to Home Assistant")
+		}
 
 		// Initialize notification service
-		notifSvc, err := notifications.NewService(telegramBot.GetAPI(), cfg, haClient)
+		notifSvc, err := notifications.NewService(telegramBot.GetAPI(), cfg, haClient, stateCache)
 		if err != nil {
 			logger.Fatal("Failed to create notification service: %v", err)
 		}
+		notifSvc.RestorePause(ctx)
+
+		// Load outage journal (falls back to in-memory if the data directory is unusable)
+		journal, err := outages.NewJournal(filepath.Join(cfg.DataDir, "outages.json"))
+		if err != nil {
+			logger.Warn("Failed to load outage journal, starting empty: %v", err)
+			journal, _ = outages.NewJournal("")
+		}
+
+		// Initialize sensor publisher if enabled
+		var publisher *sensors.Publisher
+		if cfg.PublishSensors {
+			publisher = sensors.NewPublisher(cfg, haClient, wsClient, journal)
+			go publisher.Run(ctx)
+			logger.Info("Publishing add-on sensors to Home Assistant")
+		}
 
 		// Initialize power watcher
-		powerWatcher = watcher.NewWatcher(cfg, wsClient, haClient, notifSvc)
+		powerWatcher = watcher.NewWatcher(cfg, wsClient, haClient, notifSvc, journal, publisher)
+		telegramBot.SetPowerMonitor(powerWatcher, notifSvc)
 
 		// Start power watcher in a separate goroutine
 		go func() {
@@ -89,6 +139,29 @@ func main() {
 		logger.Info("Power monitoring started")
 	} else {
 		logger.Info("Power monitoring not configured, skipping")
+
+		// Area commands still need the WebSocket connection
+		if cfg.IsBotCommandsEnabled() {
+			go func() {
+				if err := wsClient.RunWithReconnect(ctx); err != nil {
+					logger.Error("WebSocket error: %v", err)
+				}
+			}()
+		}
+	}
+
+	// Start Telegram bot command handler if enabled; started after the
+	// watcher so that /power can read it
+	if cfg.IsBotCommandsEnabled() {
+		logger.Info("Bot commands enabled for %d chat(s), %d with explicit roles", len(cfg.AllowedChatIDs), len(cfg.BotRoles))
+		go func() {
+			if err := telegramBot.Start(ctx); err != nil {
+				logger.Error("Bot error: %v", err)
+				cancel()
+			}
+		}()
+	} else {
+		logger.Info("Bot commands disabled (allowed_chat_ids is empty)")
 	}
 
 	logger.Info("Bot is running. Press Ctrl+C to stop.")
@@ -108,3 +181,54 @@ func main() {
 	telegramBot.Stop()
 	logger.Info("Bot stopped successfully")
 }
+
+// runReceiver runs the standalone heartbeat receiver: no Home Assistant, only
+// Telegram notifications when the in-house instance stops sending heartbeats
+func runReceiver(cfg *config.Config) {
+	logger.Info("Starting heartbeat receiver")
+
+	if len(cfg.NotificationChatIDs) == 0 {
+		logger.Fatal("Receiver mode requires notification_chat_ids")
+	}
+	if cfg.HeartbeatToken == "" {
+		logger.Warn("HEARTBEAT_TOKEN is empty, anyone can send heartbeats")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	telegramBot, err := bot.New(cfg, nil, nil, nil)
+	if err != nil {
+		logger.Fatal("Failed to create Telegram bot: %v", err)
+	}
+
+	notifSvc, err := notifications.NewService(telegramBot.GetAPI(), cfg, nil, nil)
+	if err != nil {
+		logger.Fatal("Failed to create notification service: %v", err)
+	}
+
+	journal, err := outages.NewJournal(filepath.Join(cfg.DataDir, "outages.json"))
+	if err != nil {
+		logger.Warn("Failed to load outage journal, starting empty: %v", err)
+		journal, _ = outages.NewJournal("")
+	}
+
+	timeout := time.Duration(cfg.HeartbeatTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 3 * time.Minute
+	}
+	receiver := heartbeat.NewReceiver(cfg.HeartbeatToken, timeout, notifSvc, journal)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		logger.Info("Shutdown signal received, stopping receiver...")
+		cancel()
+	}()
+
+	if err := receiver.Run(ctx, cfg.HeartbeatListen); err != nil {
+		logger.Fatal("Heartbeat receiver error: %v", err)
+	}
+	logger.Info("Receiver stopped successfully")
+}
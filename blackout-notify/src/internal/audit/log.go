@@ -0,0 +1,221 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Results of an audited command or button press
+const (
+	ResultOK        = "ok"
+	ResultError     = "error"
+	ResultDenied    = "denied"
+	ResultPending   = "pending" // Waiting for confirmation or a choice of entity
+	ResultCancelled = "cancelled"
+	ResultExpired   = "expired"
+)
+
+const (
+	// maxFileSize is the size in bytes beyond which the log file is rotated
+	maxFileSize = 1 << 20
+
+	// maxBackups is the number of rotated files kept: audit.log.1 (newest) to audit.log.5
+	maxBackups = 5
+
+	// maxMemoryEntries limits a log kept in memory only
+	maxMemoryEntries = 1000
+)
+
+// Entry is a single audited command or button press
+type Entry struct {
+	Time      time.Time `json:"time"`
+	ChatID    int64     `json:"chat_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username,omitempty"`
+	Command   string    `json:"command"`
+	Args      string    `json:"args,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+}
+
+// Log appends entries to a JSON Lines file and rotates it by size
+type Log struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	memory  []Entry // Entries of a log without a file
+}
+
+// New creates an audit log writing to the file at path.
+// An empty path keeps the latest entries in memory only.
+func New(path string) (*Log, error) {
+	l := &Log{path: path, maxSize: maxFileSize, backups: maxBackups}
+
+	if path == "" {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	return l, nil
+}
+
+// Record appends an entry, rotating the file first if it would grow beyond the size limit
+func (l *Log) Record(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		l.memory = append(l.memory, e)
+		if len(l.memory) > maxMemoryEntries {
+			l.memory = l.memory[len(l.memory)-maxMemoryEntries:]
+		}
+		return nil
+	}
+
+	if info, err := os.Stat(l.path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > l.maxSize {
+		if err := l.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Entries returns all retained entries, oldest first
+func (l *Log) Entries() ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		return append([]Entry(nil), l.memory...), nil
+	}
+
+	var entries []Entry
+	for i := l.backups; i >= 0; i-- {
+		data, err := os.ReadFile(l.filePath(i))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var e Entry
+			// A line cut short by a power loss is skipped
+			if err := json.Unmarshal(line, &e); err != nil {
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// Recent returns up to n latest entries, newest first
+func (l *Log) Recent(n int) ([]Entry, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	recent := make([]Entry, 0, min(n, len(entries)))
+	for i := len(entries) - 1; i >= 0 && len(recent) < n; i-- {
+		recent = append(recent, entries[i])
+	}
+	return recent, nil
+}
+
+// rotateLocked shifts audit.log to audit.log.1 and so on, dropping the oldest
+// file; caller must hold l.mu
+func (l *Log) rotateLocked() error {
+	if err := os.Remove(l.filePath(l.backups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove old audit log: %w", err)
+	}
+
+	for i := l.backups - 1; i >= 0; i-- {
+		if err := os.Rename(l.filePath(i), l.filePath(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// filePath returns the path of the current file (0) or of a rotated one
+func (l *Log) filePath(n int) string {
+	if n == 0 {
+		return l.path
+	}
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// WriteCSV writes entries as CSV with a header row
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"time", "chat_id", "user_id", "username", "command", "args", "result", "error", "latency_ms"}); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	for _, e := range entries {
+		record := []string{
+			e.Time.Format(time.RFC3339),
+			strconv.FormatInt(e.ChatID, 10),
+			strconv.FormatInt(e.UserID, 10),
+			csvText(e.Username),
+			csvText(e.Command),
+			csvText(e.Args),
+			e.Result,
+			csvText(e.Error),
+			strconv.FormatInt(e.LatencyMS, 10),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText guards user-typed text against formula injection: spreadsheets run
+// cells starting with "=", "+", "-" or "@" as formulas, so those get a "'" prefix
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
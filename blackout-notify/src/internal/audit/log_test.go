@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecent(t *testing.T) {
+	l, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, command := range []string{"power", "history", "turn_off"} {
+		if err := l.Record(Entry{Command: command, Result: ResultOK}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	recent, err := l.Recent(2)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(recent) != 2 || recent[0].Command != "turn_off" || recent[1].Command != "history" {
+		t.Errorf("Recent(2) = %+v, want turn_off, history", recent)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	l.maxSize = 200
+	l.backups = 2
+
+	start := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		e := Entry{Time: start.Add(time.Duration(i) * time.Minute), ChatID: 123, Command: "power", Result: ResultOK}
+		if err := l.Record(e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+		if info.Size() > l.maxSize {
+			t.Errorf("%s size = %d, want at most %d", name, info.Size(), l.maxSize)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected no more than 2 rotated files")
+	}
+
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(entries) == 0 || len(entries) >= 20 {
+		t.Fatalf("Entries() returned %d entries, want the newest part of 20", len(entries))
+	}
+	if last := entries[len(entries)-1]; !last.Time.Equal(start.Add(19 * time.Minute)) {
+		t.Errorf("Last entry at %v, want the newest one", last.Time)
+	}
+	for i := 1; i < len(entries); i++ {
+		if !entries[i].Time.After(entries[i-1].Time) {
+			t.Fatal("Entries() should be oldest first")
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries := []Entry{{
+		Time:      time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC),
+		ChatID:    -100,
+		UserID:    42,
+		Username:  "@alice",
+		Command:   "call",
+		Args:      `notify.phone message="Power, back"`,
+		Result:    ResultError,
+		Error:     "service not found",
+		LatencyMS: 120,
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WriteCSV() wrote %d lines, want header and 1 row", len(lines))
+	}
+	want := `2026-01-04T10:00:00Z,-100,42,'@alice,call,"notify.phone message=""Power, back""",error,service not found,120`
+	if lines[1] != want {
+		t.Errorf("CSV row = %s, want %s", lines[1], want)
+	}
+}
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"light.kitchen", "light.kitchen"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+1", "'+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := csvText(tt.text); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+)
+
+// commandRoles lists commands that need more than the viewer role
+var commandRoles = map[string]string{
+	"turn_on":  config.RoleOperator,
+	"on":       config.RoleOperator,
+	"turn_off": config.RoleOperator,
+	"off":      config.RoleOperator,
+	"toggle":   config.RoleOperator,
+	"pause":    config.RoleOperator,
+	"resume":   config.RoleOperator,
+	"call":     config.RoleAdmin,
+	"template": config.RoleAdmin, // Templates read any entity, past entity_allow / entity_deny
+	"audit":    config.RoleAdmin,
+}
+
+// callbackRoles lists callback actions that need more than the viewer role
+var callbackRoles = map[string]string{
+	callbackService:             config.RoleOperator,
+	notifications.CallbackPause: config.RoleOperator,
+}
+
+// userID returns the ID of a message author; 0 for channel posts
+func userID(user *tgbotapi.User) int64 {
+	if user == nil {
+		return 0
+	}
+	return user.ID
+}
+
+// requiredRole returns the role needed for a command or callback action
+func requiredRole(roles map[string]string, name string) string {
+	if role, ok := roles[name]; ok {
+		return role
+	}
+	return config.RoleViewer
+}
+
+// checkEntity rejects entities excluded by the entity_allow / entity_deny patterns.
+// Must be called before any service call on behalf of a user.
+func (b *Bot) checkEntity(entityID string) error {
+	if !b.config.IsEntityAllowed(entityID) {
+		return fmt.Errorf("entity %s is not allowed to be controlled from Telegram", entityID)
+	}
+	return nil
+}
+
+// checkTarget checks every entity of a service call target, including the
+// entities of its areas and devices while patterns are set. While patterns are
+// set, calls without a target or on all entities are refused, as the entities
+// they affect can't be checked.
+func (b *Bot) checkTarget(ctx context.Context, target homeassistant.ServiceTarget) error {
+	if b.config.HasEntityRestrictions() {
+		if target.IsEmpty() {
+			return fmt.Errorf("service calls without a target are not allowed while entity_allow / entity_deny are set")
+		}
+		if target.TargetsAll() {
+			return fmt.Errorf("service calls on all entities are not allowed while entity_allow / entity_deny are set")
+		}
+	}
+
+	ids := target.EntityIDs
+	if b.config.HasEntityRestrictions() && (len(target.AreaIDs) > 0 || len(target.DeviceIDs) > 0) {
+		reg, err := b.registry(ctx)
+		if err != nil {
+			return fmt.Errorf("area and device targets can't be checked against entity_allow / entity_deny: %w", err)
+		}
+		ids = reg.ExpandTarget(target)
+	}
+
+	for _, id := range ids {
+		if err := b.checkEntity(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+const (
+	// areaPrefix marks an area instead of an entity in /turn_on, /turn_off and /toggle: "area:bedroom"
+	areaPrefix = "area:"
+
+	// callbackArea prefixes callback data of area choice buttons: "area:<area_id>"
+	callbackArea = "area"
+)
+
+// areaServiceVerbs describe the result of an area service call
+var areaServiceVerbs = map[string]string{
+	"turn_on":  "Turned ON",
+	"turn_off": "Turned OFF",
+	"toggle":   "Toggled",
+}
+
+// escapeMarkdown escapes names from Home Assistant for Markdown messages.
+// Legacy Markdown allows no escapes inside entities, so names are kept out of them.
+func escapeMarkdown(s string) string {
+	return tgbotapi.EscapeText(tgbotapi.ModeMarkdown, s)
+}
+
+// registry reads the area, device and entity registries of Home Assistant
+func (b *Bot) registry(ctx context.Context) (*homeassistant.Registry, error) {
+	if b.ws == nil || !b.ws.IsConnected() {
+		return nil, fmt.Errorf("areas need the WebSocket connection to Home Assistant, try again later")
+	}
+	return b.ws.GetRegistry(ctx)
+}
+
+// findArea resolves an area by ID, name or alias. Ambiguous names return the candidates instead.
+func findArea(reg *homeassistant.Registry, query string) (homeassistant.Area, []homeassistant.Area, error) {
+	q := normalizeName(query)
+
+	var best []homeassistant.Area
+	bestMatch := matchNone
+	for _, a := range reg.Areas {
+		switch m := matchArea(q, a); {
+		case m > bestMatch:
+			best, bestMatch = []homeassistant.Area{a}, m
+		case m == bestMatch && m != matchNone:
+			best = append(best, a)
+		}
+	}
+
+	switch len(best) {
+	case 0:
+		return homeassistant.Area{}, nil, fmt.Errorf("no area matches %q, see /area", query)
+	case 1:
+		return best[0], nil, nil
+	default:
+		sort.Slice(best, func(i, j int) bool { return best[i].Name < best[j].Name })
+		return homeassistant.Area{}, best, nil
+	}
+}
+
+// matchArea rates how well a normalized search matches an area ID, name or alias
+func matchArea(q string, a homeassistant.Area) int {
+	if q == "" {
+		return matchNone
+	}
+
+	best := matchNone
+	for _, name := range append([]string{a.AreaID, a.Name}, a.Aliases...) {
+		name = normalizeName(name)
+		switch {
+		case name == q:
+			return matchExact
+		case strings.HasPrefix(name, q):
+			best = max(best, matchPrefix)
+		case containsWords(name, q):
+			best = max(best, matchWords)
+		}
+	}
+	return best
+}
+
+// handleArea lists areas with their entity counts, or the entity states of one area
+func (b *Bot) handleArea(ctx context.Context, args string, withButtons bool) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	reg, err := b.registry(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if strings.TrimSpace(args) == "" {
+		return formatAreas(reg), nil, nil
+	}
+
+	area, candidates, err := findArea(reg, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(candidates) > 0 {
+		return fmt.Sprintf("🔎 Several areas match `%s`, choose one:", strings.TrimSpace(args)), areaChoiceKeyboard(candidates), nil
+	}
+
+	return b.renderArea(ctx, reg, area, withButtons)
+}
+
+// handleAreaCallback shows the area chosen from an ambiguous /area search
+func (b *Bot) handleAreaCallback(ctx context.Context, query *tgbotapi.CallbackQuery, areaID string) (string, error) {
+	reg, err := b.registry(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	area, ok := reg.Area(areaID)
+	if !ok {
+		return "", fmt.Errorf("area %s no longer exists", areaID)
+	}
+
+	role := b.config.MemberRole(query.Message.Chat.ID, userID(query.From))
+	text, keyboard, err := b.renderArea(ctx, reg, area, config.RoleAtLeast(role, requiredRole(callbackRoles, callbackService)))
+	if err != nil {
+		return "", err
+	}
+
+	b.editMessage(query.Message.Chat.ID, query.Message.MessageID, text, keyboard)
+	return "", nil
+}
+
+// formatAreas lists all areas by name with their number of entities
+func formatAreas(reg *homeassistant.Registry) string {
+	if len(reg.Areas) == 0 {
+		return "No areas defined in Home Assistant."
+	}
+
+	areas := append([]homeassistant.Area(nil), reg.Areas...)
+	sort.Slice(areas, func(i, j int) bool { return areas[i].Name < areas[j].Name })
+
+	var sb strings.Builder
+	sb.WriteString("🏠 *Areas:*\n\n")
+	for _, a := range areas {
+		sb.WriteString(fmt.Sprintf("• %s (`%s`): %d\n", escapeMarkdown(a.Name), a.AreaID, len(reg.AreaEntities(a.AreaID))))
+	}
+	sb.WriteString("\nUse `/area <name>` to list an area")
+	return sb.String()
+}
+
+// renderArea lists the entity states of an area; withButtons adds control buttons
+func (b *Bot) renderArea(ctx context.Context, reg *homeassistant.Registry, area homeassistant.Area, withButtons bool) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	states, err := b.states.GetStates(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	byID := make(map[string]homeassistant.Entity, len(states))
+	for _, e := range states {
+		byID[e.EntityID] = e
+	}
+
+	var entities []homeassistant.Entity
+	for _, id := range reg.AreaEntities(area.AreaID) {
+		if e, ok := byID[id]; ok {
+			entities = append(entities, e)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🏠 %s\n\n", escapeMarkdown(area.Name)))
+	if len(entities) == 0 {
+		sb.WriteString("No entities in this area.")
+		return sb.String(), nil, nil
+	}
+
+	for i, e := range entities {
+		if i >= maxEntitiesShown {
+			sb.WriteString(fmt.Sprintf("\n... and %d more", len(entities)-maxEntitiesShown))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s `%s`: %s\n", getStateIcon(e.State), e.EntityID, e.State))
+	}
+	sb.WriteString(fmt.Sprintf("\nTurn everything off: `/off %s%s`", areaPrefix, area.AreaID))
+
+	if !withButtons {
+		return sb.String(), nil, nil
+	}
+	return sb.String(), entitiesKeyboard(entities[:min(len(entities), maxEntitiesShown)], area.AreaID, b.config.IsEntityAllowed), nil
+}
+
+// areaChoiceKeyboard offers the candidates of an ambiguous area search
+func areaChoiceKeyboard(areas []homeassistant.Area) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, a := range areas {
+		if button, ok := callbackButton(a.Name, callbackArea, a.AreaID); ok {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// areaTargets resolves an area search to the controllable entities in it,
+// see areaTargetsIn
+func (b *Bot) areaTargets(ctx context.Context, query string) (homeassistant.Area, []string, int, error) {
+	reg, err := b.registry(ctx)
+	if err != nil {
+		return homeassistant.Area{}, nil, 0, err
+	}
+	return b.areaTargetsIn(reg, query)
+}
+
+// areaTargetsIn resolves an area search to the controllable entities in it.
+// Entities excluded by entity_allow / entity_deny are skipped and counted.
+func (b *Bot) areaTargetsIn(reg *homeassistant.Registry, query string) (homeassistant.Area, []string, int, error) {
+	area, candidates, err := findArea(reg, query)
+	if err != nil {
+		return homeassistant.Area{}, nil, 0, err
+	}
+	if len(candidates) > 0 {
+		names := make([]string, len(candidates))
+		for i, a := range candidates {
+			names[i] = escapeMarkdown(a.Name)
+		}
+		return homeassistant.Area{}, nil, 0, fmt.Errorf("several areas match %q: %s", query, strings.Join(names, ", "))
+	}
+
+	var ids []string
+	skipped := 0
+	for _, id := range reg.AreaEntities(area.AreaID) {
+		if !controllableDomains[getDomain(id)] {
+			continue
+		}
+		if !b.config.IsEntityAllowed(id) {
+			skipped++
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return area, ids, skipped, nil
+}
+
+// handleAreaService turns on, off or toggles every controllable entity of an area
+func (b *Bot) handleAreaService(ctx context.Context, service, query string) (string, error) {
+	area, ids, skipped, err := b.areaTargets(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no entities to control in %s", escapeMarkdown(area.Name))
+	}
+
+	if _, err := b.haClient.CallServiceWithData(ctx, "homeassistant", service, homeassistant.EntityTarget(ids...), nil); err != nil {
+		return "", err
+	}
+	b.states.Invalidate(ids...)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✅ %s %d in %s:\n", areaServiceVerbs[service], len(ids), escapeMarkdown(area.Name)))
+	for i, id := range ids {
+		if i >= maxChangedStatesShown {
+			sb.WriteString(fmt.Sprintf("... and %d more\n", len(ids)-maxChangedStatesShown))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("`%s`\n", id))
+	}
+	if skipped > 0 {
+		sb.WriteString(fmt.Sprintf("\n_%d not allowed, skipped_", skipped))
+	}
+	return sb.String(), nil
+}
+
+// areaNeedsConfirmation checks if an area contains entities matching confirm_entities.
+// Areas that can't be resolved run right away to report the error.
+func (b *Bot) areaNeedsConfirmation(ctx context.Context, query string) bool {
+	_, ids, _, err := b.areaTargets(ctx, query)
+	if err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if b.config.NeedsConfirmation(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+func testAreaRegistry() *homeassistant.Registry {
+	return &homeassistant.Registry{
+		Areas: []homeassistant.Area{
+			{AreaID: "bedroom", Name: "Bedroom", Aliases: []string{"спальня"}},
+			{AreaID: "kids_bedroom", Name: "Kids_Bedroom *2*"},
+			{AreaID: "kitchen", Name: "Kitchen"},
+			{AreaID: "living_room", Name: "Living room"},
+			{AreaID: "dining_room", Name: "Dining room"},
+		},
+		Entities: []homeassistant.RegistryEntry{
+			{EntityID: "light.bedroom_ceiling", AreaID: "bedroom"},
+			{EntityID: "switch.bedroom_fan", AreaID: "bedroom"},
+			{EntityID: "lock.bedroom_window", AreaID: "bedroom"},
+			{EntityID: "sensor.bedroom_temperature", AreaID: "bedroom"},
+			{EntityID: "light.kitchen", AreaID: "kitchen"},
+		},
+	}
+}
+
+func TestMatchArea(t *testing.T) {
+	area := homeassistant.Area{AreaID: "living_room", Name: "Living room", Aliases: []string{"Вітальня", "lounge"}}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"living_room", matchExact},
+		{"LIVING ROOM", matchExact},
+		{"вітальня", matchExact},
+		{"liv", matchPrefix},
+		{"lou", matchPrefix},
+		{"room", matchWords},
+		{"room living", matchWords},
+		{"kitchen", matchNone},
+		{"", matchNone},
+	}
+
+	for _, tt := range tests {
+		if got := matchArea(normalizeName(tt.query), area); got != tt.want {
+			t.Errorf("matchArea(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFindArea(t *testing.T) {
+	reg := testAreaRegistry()
+
+	tests := []struct {
+		name       string
+		query      string
+		want       string
+		candidates []string
+		wantErr    bool
+	}{
+		{name: "exact ID", query: "kitchen", want: "kitchen"},
+		{name: "exact name beats prefix of another area", query: "bedroom", want: "bedroom"},
+		{name: "alias", query: "Спальня", want: "bedroom"},
+		{name: "prefix", query: "kit", want: "kitchen"},
+		{name: "prefix beats word match", query: "kids", want: "kids_bedroom"},
+		// Words match inside names too: "room" is in "Bedroom"
+		{name: "ambiguous word match sorted by name", query: "room", candidates: []string{"bedroom", "dining_room", "kids_bedroom", "living_room"}},
+		{name: "all words must match", query: "room din", want: "dining_room"},
+		{name: "no match", query: "garage", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area, candidates, err := findArea(reg, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findArea(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+
+			var ids []string
+			for _, a := range candidates {
+				ids = append(ids, a.AreaID)
+			}
+			if !reflect.DeepEqual(ids, tt.candidates) {
+				t.Errorf("findArea(%q) candidates = %v, want %v", tt.query, ids, tt.candidates)
+			}
+			if area.AreaID != tt.want {
+				t.Errorf("findArea(%q) = %q, want %q", tt.query, area.AreaID, tt.want)
+			}
+		})
+	}
+}
+
+func TestAreaTargetsIn(t *testing.T) {
+	reg := testAreaRegistry()
+
+	tests := []struct {
+		name    string
+		config  *config.Config
+		query   string
+		want    []string
+		skipped int
+		wantErr bool
+	}{
+		{
+			name:   "controllable entities only",
+			config: &config.Config{},
+			query:  "bedroom",
+			want:   []string{"light.bedroom_ceiling", "switch.bedroom_fan"},
+		},
+		{
+			name:    "denied entities skipped",
+			config:  &config.Config{EntityDeny: []string{"switch.*"}},
+			query:   "bedroom",
+			want:    []string{"light.bedroom_ceiling"},
+			skipped: 1,
+		},
+		{
+			name:    "not allowed entities skipped",
+			config:  &config.Config{EntityAllow: []string{"switch.*"}},
+			query:   "bedroom",
+			want:    []string{"switch.bedroom_fan"},
+			skipped: 1,
+		},
+		{
+			name:    "ambiguous area",
+			config:  &config.Config{},
+			query:   "room",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bot{config: tt.config}
+			_, ids, skipped, err := b.areaTargetsIn(reg, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("areaTargetsIn(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ids, tt.want) || skipped != tt.skipped {
+				t.Errorf("areaTargetsIn(%q) = %v, %d skipped, want %v, %d skipped", tt.query, ids, skipped, tt.want, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestFormatAreasEscapesNames(t *testing.T) {
+	text := formatAreas(testAreaRegistry())
+
+	if !strings.Contains(text, `• Kids\_Bedroom \*2\* (`+"`kids_bedroom`"+`): 0`) {
+		t.Errorf("formatAreas() = %q, want escaped area name", text)
+	}
+}
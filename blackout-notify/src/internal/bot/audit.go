@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/audit"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+const (
+	// defaultAuditShown and maxAuditShown limit the entries listed by /audit
+	defaultAuditShown = 20
+	maxAuditShown     = 30
+
+	// maxAuditArgs limits arguments shown per /audit line, in characters
+	maxAuditArgs = 40
+
+	// auditButton is the command of audited button presses; their args are the callback data
+	auditButton = "button"
+
+	// auditText is the command of audited messages that aren't commands; their args are the text
+	auditText = "text"
+)
+
+// auditIcons mark the result of each /audit line
+var auditIcons = map[string]string{
+	audit.ResultOK:        "✅",
+	audit.ResultError:     "❌",
+	audit.ResultDenied:    "⛔",
+	audit.ResultPending:   "⏳",
+	audit.ResultCancelled: "✖️",
+	audit.ResultExpired:   "⌛",
+}
+
+// SetAuditLog enables recording of commands and button presses
+func (b *Bot) SetAuditLog(log *audit.Log) {
+	b.auditLog = log
+}
+
+// record writes an audit entry; latency is measured from entry.Time
+func (b *Bot) record(entry audit.Entry) {
+	if b.auditLog == nil {
+		return
+	}
+
+	entry.LatencyMS = time.Since(entry.Time).Milliseconds()
+	if err := b.auditLog.Record(entry); err != nil {
+		logger.Warn("Failed to write audit log: %v", err)
+	}
+}
+
+// auditCommand returns the command and arguments a message is audited with
+func auditCommand(message *tgbotapi.Message) (string, string) {
+	if !message.IsCommand() {
+		return auditText, message.Text
+	}
+	return message.Command(), message.CommandArguments()
+}
+
+// handleAudit lists the latest audit entries or sends all of them as CSV: /audit [N|csv]
+func (b *Bot) handleAudit(chatID int64, args string) (string, error) {
+	if b.auditLog == nil {
+		return "Audit log is not available.", nil
+	}
+
+	args = strings.ToLower(strings.TrimSpace(args))
+	if args == "csv" {
+		return b.exportAudit(chatID)
+	}
+
+	n := defaultAuditShown
+	if args != "" {
+		var err error
+		if n, err = strconv.Atoi(args); err != nil || n < 1 || n > maxAuditShown {
+			return "", fmt.Errorf("use /audit [1-%d] or /audit csv", maxAuditShown)
+		}
+	}
+
+	entries, err := b.auditLog.Recent(n)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "Audit log is empty.", nil
+	}
+
+	loc := time.Local
+	if b.notifSvc != nil {
+		loc = b.notifSvc.Location()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧾 *Last %d commands*\n\n", len(entries)))
+	for _, e := range entries {
+		sb.WriteString(formatAuditEntry(e, loc))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nFull log: `/audit csv`")
+	return sb.String(), nil
+}
+
+// exportAudit sends all retained audit entries as a CSV document
+func (b *Bot) exportAudit(chatID int64) (string, error) {
+	entries, err := b.auditLog.Entries()
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := audit.WriteCSV(&buf, entries); err != nil {
+		return "", err
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("audit-%s.csv", time.Now().Format("2006-01-02")),
+		Bytes: buf.Bytes(),
+	})
+	if _, err := b.api.Send(doc); err != nil {
+		return "", fmt.Errorf("failed to send audit log: %w", err)
+	}
+
+	return fmt.Sprintf("📄 Exported %d audit entries", len(entries)), nil
+}
+
+// formatAuditEntry formats one /audit line
+func formatAuditEntry(e audit.Entry, loc *time.Location) string {
+	icon := auditIcons[e.Result]
+	if icon == "" {
+		icon = "•"
+	}
+
+	who := e.Username
+	if who == "" {
+		who = strconv.FormatInt(e.UserID, 10)
+	}
+
+	command := e.Command
+	if command != auditButton && command != auditText {
+		command = "/" + command
+	}
+	if args := []rune(strings.ReplaceAll(e.Args, "`", "'")); len(args) > maxAuditArgs {
+		command += " " + string(args[:maxAuditArgs-1]) + "…"
+	} else if len(args) > 0 {
+		command += " " + string(args)
+	}
+
+	return fmt.Sprintf("%s `%s` `%s` `%s` %dms", icon, e.Time.In(loc).Format("02.01 15:04"), who, command, e.LatencyMS)
+}
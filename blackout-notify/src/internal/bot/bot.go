@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/audit"
 	"github.com/yourusername/haaddon/telegram-bot/internal/config"
 	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
 	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+	"github.com/yourusername/haaddon/telegram-bot/internal/watcher"
 )
 
 // Bot represents a Telegram bot
@@ -16,11 +21,22 @@ type Bot struct {
 	api      *tgbotapi.BotAPI
 	config   *config.Config
 	haClient *homeassistant.Client
+	ws       *homeassistant.WSClient
+	states   *homeassistant.StateCache
+	watcher  *watcher.Watcher
+	notifSvc *notifications.Service
+	auditLog *audit.Log
 	stopChan chan struct{}
+
+	// Commands waiting for confirmation, see requestConfirmation
+	pendingMu     sync.Mutex
+	pending       map[int]*pendingAction
+	nextPendingID int
 }
 
-// New creates a new Telegram bot
-func New(cfg *config.Config, haClient *homeassistant.Client) (*Bot, error) {
+// New creates a new Telegram bot. Entity states are read from states;
+// area commands read the registries over ws.
+func New(cfg *config.Config, haClient *homeassistant.Client, ws *homeassistant.WSClient, states *homeassistant.StateCache) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(cfg.TelegramToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
@@ -32,7 +48,10 @@ func New(cfg *config.Config, haClient *homeassistant.Client) (*Bot, error) {
 		api:      api,
 		config:   cfg,
 		haClient: haClient,
+		ws:       ws,
+		states:   states,
 		stopChan: make(chan struct{}),
+		pending:  make(map[int]*pendingAction),
 	}, nil
 }
 
@@ -55,14 +74,28 @@ func (b *Bot) Start(ctx context.Context) error {
 		case <-b.stopChan:
 			return nil
 		case update := <-updates:
+			if update.CallbackQuery != nil {
+				b.dispatchCallback(ctx, update.CallbackQuery)
+				continue
+			}
 			if update.Message == nil {
 				continue
 			}
 
 			// Check if chat ID is allowed
-			if !b.config.IsChatAllowed(update.Message.Chat.ID) {
-				logger.Warn("Unauthorized access attempt from chat ID: %d", update.Message.Chat.ID)
-				if len(b.config.AllowedChatIDs) == 0 {
+			if b.config.MemberRole(update.Message.Chat.ID, userID(update.Message.From)) == "" {
+				logger.Warn("Unauthorized access attempt from chat ID: %d, user ID: %d", update.Message.Chat.ID, userID(update.Message.From))
+				command, args := auditCommand(update.Message)
+				b.record(audit.Entry{
+					Time:     time.Now(),
+					ChatID:   update.Message.Chat.ID,
+					UserID:   userID(update.Message.From),
+					Username: userName(update.Message.From),
+					Command:  command,
+					Args:     args,
+					Result:   audit.ResultDenied,
+				})
+				if !b.config.IsBotCommandsEnabled() {
 					b.sendMessage(update.Message.Chat.ID, "⛔ Bot commands are disabled. Configure allowed_chat_ids to enable.")
 				} else {
 					b.sendMessage(update.Message.Chat.ID, "⛔ Access denied. Your chat ID is not in the allowed list.")
@@ -83,17 +116,81 @@ func (b *Bot) Stop() {
 }
 
 func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
-	if !message.IsCommand() {
-		b.sendMessage(message.Chat.ID, "Please use commands. Type /help for available commands.")
+	command, args, isAlias := parseAlias(message.Text)
+	if !isAlias {
+		if !message.IsCommand() {
+			command, args := auditCommand(message)
+			b.record(audit.Entry{
+				Time:     time.Now(),
+				ChatID:   message.Chat.ID,
+				UserID:   userID(message.From),
+				Username: userName(message.From),
+				Command:  command,
+				Args:     args,
+				Result:   audit.ResultError,
+				Error:    "not a command",
+			})
+			b.sendMessage(message.Chat.ID, "Please use commands. Type /help for available commands.")
+			return
+		}
+
+		command = message.Command()
+		args = message.CommandArguments()
+	}
+
+	logger.Debug("Received command: /%s %s from chat %d", command, args, message.Chat.ID)
+
+	entry := audit.Entry{
+		Time:     time.Now(),
+		ChatID:   message.Chat.ID,
+		UserID:   userID(message.From),
+		Username: userName(message.From),
+		Command:  command,
+		Args:     args,
+	}
+
+	role := b.config.MemberRole(message.Chat.ID, userID(message.From))
+	if required := requiredRole(commandRoles, command); !config.RoleAtLeast(role, required) {
+		logger.Warn("User %d in chat %d (%s) denied /%s, requires %s", userID(message.From), message.Chat.ID, role, command, required)
+		entry.Result = audit.ResultDenied
+		b.record(entry)
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("⛔ /%s requires the %s role.", command, required))
 		return
 	}
 
-	command := message.Command()
-	args := message.CommandArguments()
+	// Aliases and entity names become entity IDs; ambiguous names offer a choice
+	if entityCommands[command] && strings.TrimSpace(args) != "" {
+		var allowed func(entityID string) bool
+		if command != "state" {
+			allowed = b.config.IsEntityAllowed
+		}
 
-	logger.Debug("Received command: /%s %s from chat %d", command, args, message.Chat.ID)
+		resolved, candidates, err := b.resolveEntity(ctx, args, allowed)
+		if err != nil {
+			entry.Result, entry.Error = audit.ResultError, err.Error()
+			b.record(entry)
+			b.sendMessage(message.Chat.ID, fmt.Sprintf("❌ Error: %s", err.Error()))
+			return
+		}
+		if len(candidates) > 0 {
+			entry.Result = audit.ResultPending
+			b.record(entry)
+			b.sendMessageWithKeyboard(message.Chat.ID, fmt.Sprintf("🔎 Several entities match `%s`, choose one:", strings.TrimSpace(args)), entityChoiceKeyboard(command, candidates))
+			return
+		}
+		args = resolved
+		entry.Args = resolved
+	}
+
+	if run := b.confirmable(ctx, command, args); run != nil {
+		entry.Result = audit.ResultPending
+		b.record(entry)
+		b.requestConfirmation(message.Chat.ID, message.From, command, args, run)
+		return
+	}
 
 	var response string
+	var keyboard *tgbotapi.InlineKeyboardMarkup
 	var err error
 
 	switch command {
@@ -103,39 +200,84 @@ func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 		response = b.handleHelp()
 	case "status":
 		response, err = b.handleStatus(ctx)
+	case "power":
+		response = b.handlePower(ctx)
+	case "history":
+		response, keyboard, err = b.handleHistory(ctx, args)
+	case "pause":
+		response, err = b.handlePause(ctx, args, message.From)
+	case "resume":
+		response, err = b.handleResume(ctx, message.From)
 	case "entities":
-		response, err = b.handleEntities(ctx, args)
+		response, keyboard, err = b.handleEntities(ctx, args, config.RoleAtLeast(role, requiredRole(callbackRoles, callbackService)))
+	case "area":
+		response, keyboard, err = b.handleArea(ctx, args, config.RoleAtLeast(role, requiredRole(callbackRoles, callbackService)))
 	case "state":
 		response, err = b.handleState(ctx, args)
+		keyboard = stateKeyboard(args)
 	case "turn_on", "on":
 		response, err = b.handleTurnOn(ctx, args)
 	case "turn_off", "off":
 		response, err = b.handleTurnOff(ctx, args)
 	case "toggle":
 		response, err = b.handleToggle(ctx, args)
+	case "call":
+		response, err = b.handleCall(ctx, args)
+	case "template":
+		response, err = b.handleTemplate(ctx, args)
+	case "audit":
+		response, err = b.handleAudit(message.Chat.ID, args)
 	case "chatid":
-		response = fmt.Sprintf("Your chat ID: `%d`", message.Chat.ID)
+		response = fmt.Sprintf("Your chat ID: `%d`\nYour user ID: `%d`\nRole: %s", message.Chat.ID, userID(message.From), role)
 	default:
 		response = fmt.Sprintf("Unknown command: /%s\nType /help for available commands.", command)
 	}
 
+	entry.Result = audit.ResultOK
+	if err != nil {
+		entry.Result = audit.ResultError
+		entry.Error = err.Error()
+	}
+	b.record(entry)
+
 	if err != nil {
 		response = fmt.Sprintf("❌ Error: %s", err.Error())
 		logger.Error("Command /%s failed: %v", command, err)
+		keyboard = nil
 	}
 
-	b.sendMessage(message.Chat.ID, response)
+	b.sendMessageWithKeyboard(message.Chat.ID, response, keyboard)
 }
 
 func (b *Bot) sendMessage(chatID int64, text string) {
+	b.sendMessageWithKeyboard(chatID, text, nil)
+}
+
+// sendMessageWithKeyboard sends a message with an optional inline keyboard
+func (b *Bot) sendMessageWithKeyboard(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = tgbotapi.ModeMarkdown
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
 
 	if _, err := b.api.Send(msg); err != nil {
 		logger.Error("Failed to send message: %v", err)
 	}
 }
 
+// editMessage replaces the text and inline keyboard of a sent message
+func (b *Bot) editMessage(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = tgbotapi.ModeMarkdown
+	edit.ReplyMarkup = keyboard
+
+	// Refreshing an unchanged state is rejected by Telegram, that's fine
+	if _, err := b.api.Send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		logger.Error("Failed to edit message: %v", err)
+	}
+}
+
 func (b *Bot) handleStart() string {
 	return `🏠 *Welcome to Home Assistant Telegram Bot!*
 
@@ -148,22 +290,37 @@ func (b *Bot) handleHelp() string {
 	return `📋 *Available Commands:*
 
 *General:*
+/power (/світло) - Power status and schedule
+/history [period] - Outages for today, yesterday, week, month or N days
+/pause [duration] - Pause power notifications (e.g. 2h, 30m)
+/resume - Resume power notifications
 /status - Home Assistant status
 /chatid - Show your chat ID
 
 *Entities:*
 /entities [domain] - List entities (optionally filter by domain)
 /state <entity_id> - Get entity state
+/area [name] - List areas, or the entities of an area
+_Entities can also be given by alias or name, e.g._ ` + "`/off fan`" + `
+
+*Templates:*
+/template <jinja> - Render a Home Assistant template (admin)
 
 *Control:*
 /turn_on <entity_id> - Turn on entity
 /turn_off <entity_id> - Turn off entity  
 /toggle <entity_id> - Toggle entity
+_Use_ area:<name> _to control a whole area_
+/call <domain.service> [targets] [key=value] - Call any service
+/audit [N|csv] - Recent commands, or all as CSV (admin)
 
 *Examples:*
 ` + "`/entities light`" + `
 ` + "`/state light.living_room`" + `
-` + "`/turn_on switch.bedroom_fan`"
+` + "`/turn_on switch.bedroom_fan`" + `
+` + "`/off area:bedroom`" + `
+` + "`/call light.turn_on light.kitchen brightness_pct=50`" + `
+` + "`/template {{ states('sensor.ups_battery') }}%`"
 }
 
 func (b *Bot) handleStatus(ctx context.Context) (string, error) {
@@ -172,7 +329,7 @@ func (b *Bot) handleStatus(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("Home Assistant is not reachable: %v", err)
 	}
 
-	entities, err := b.haClient.GetStates(ctx)
+	entities, err := b.states.GetStates(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -183,22 +340,23 @@ func (b *Bot) handleStatus(ctx context.Context) (string, error) {
 📊 Total entities: %d`, len(entities)), nil
 }
 
-func (b *Bot) handleEntities(ctx context.Context, args string) (string, error) {
+// handleEntities lists entities; withButtons adds control buttons to a domain listing
+func (b *Bot) handleEntities(ctx context.Context, args string, withButtons bool) (string, *tgbotapi.InlineKeyboardMarkup, error) {
 	var entities []homeassistant.Entity
 	var err error
 
 	if args != "" {
-		entities, err = b.haClient.GetEntitiesByDomain(ctx, args)
+		entities, err = b.states.GetEntitiesByDomain(ctx, args)
 	} else {
-		entities, err = b.haClient.GetStates(ctx)
+		entities, err = b.states.GetStates(ctx)
 	}
 
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	if len(entities) == 0 {
-		return "No entities found.", nil
+		return "No entities found.", nil, nil
 	}
 
 	// Group by domains
@@ -216,8 +374,8 @@ func (b *Bot) handleEntities(ctx context.Context, args string) (string, error) {
 		// Show first 20 entities
 		count := 0
 		for _, e := range entities {
-			if count >= 20 {
-				sb.WriteString(fmt.Sprintf("\n... and %d more", len(entities)-20))
+			if count >= maxEntitiesShown {
+				sb.WriteString(fmt.Sprintf("\n... and %d more", len(entities)-maxEntitiesShown))
 				break
 			}
 			icon := getStateIcon(e.State)
@@ -230,9 +388,13 @@ func (b *Bot) handleEntities(ctx context.Context, args string) (string, error) {
 		}
 		sb.WriteString(fmt.Sprintf("\nTotal: %d entities", len(entities)))
 		sb.WriteString("\n\nUse `/entities <domain>` to list specific domain")
+		return sb.String(), nil, nil
 	}
 
-	return sb.String(), nil
+	if !withButtons {
+		return sb.String(), nil, nil
+	}
+	return sb.String(), entitiesKeyboard(entities[:min(len(entities), maxEntitiesShown)], "", b.config.IsEntityAllowed), nil
 }
 
 func (b *Bot) handleState(ctx context.Context, entityID string) (string, error) {
@@ -240,7 +402,7 @@ func (b *Bot) handleState(ctx context.Context, entityID string) (string, error)
 		return "", fmt.Errorf("please provide entity_id: /state <entity_id>")
 	}
 
-	entity, err := b.haClient.GetState(ctx, entityID)
+	entity, err := b.states.GetState(ctx, entityID)
 	if err != nil {
 		return "", err
 	}
@@ -272,10 +434,17 @@ func (b *Bot) handleTurnOn(ctx context.Context, entityID string) (string, error)
 	if entityID == "" {
 		return "", fmt.Errorf("please provide entity_id: /turn_on <entity_id>")
 	}
+	if area, ok := strings.CutPrefix(strings.TrimSpace(entityID), areaPrefix); ok {
+		return b.handleAreaService(ctx, "turn_on", area)
+	}
+	if err := b.checkEntity(entityID); err != nil {
+		return "", err
+	}
 
 	if err := b.haClient.TurnOn(ctx, entityID); err != nil {
 		return "", err
 	}
+	b.states.Invalidate(entityID)
 
 	return fmt.Sprintf("✅ Turned ON: `%s`", entityID), nil
 }
@@ -284,10 +453,17 @@ func (b *Bot) handleTurnOff(ctx context.Context, entityID string) (string, error
 	if entityID == "" {
 		return "", fmt.Errorf("please provide entity_id: /turn_off <entity_id>")
 	}
+	if area, ok := strings.CutPrefix(strings.TrimSpace(entityID), areaPrefix); ok {
+		return b.handleAreaService(ctx, "turn_off", area)
+	}
+	if err := b.checkEntity(entityID); err != nil {
+		return "", err
+	}
 
 	if err := b.haClient.TurnOff(ctx, entityID); err != nil {
 		return "", err
 	}
+	b.states.Invalidate(entityID)
 
 	return fmt.Sprintf("✅ Turned OFF: `%s`", entityID), nil
 }
@@ -296,14 +472,39 @@ func (b *Bot) handleToggle(ctx context.Context, entityID string) (string, error)
 	if entityID == "" {
 		return "", fmt.Errorf("please provide entity_id: /toggle <entity_id>")
 	}
+	if area, ok := strings.CutPrefix(strings.TrimSpace(entityID), areaPrefix); ok {
+		return b.handleAreaService(ctx, "toggle", area)
+	}
+	if err := b.checkEntity(entityID); err != nil {
+		return "", err
+	}
 
 	if err := b.haClient.Toggle(ctx, entityID); err != nil {
 		return "", err
 	}
+	b.states.Invalidate(entityID)
 
 	return fmt.Sprintf("✅ Toggled: `%s`", entityID), nil
 }
 
+func (b *Bot) handleTemplate(ctx context.Context, template string) (string, error) {
+	if strings.TrimSpace(template) == "" {
+		return "", fmt.Errorf("please provide template: /template {{ states('sensor.temperature') }}")
+	}
+
+	rendered, err := b.haClient.RenderTemplate(ctx, template, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(rendered) == "" {
+		return "_(empty result)_", nil
+	}
+
+	// The reply is sent as Markdown, keep _ and * in states literal
+	return escapeMarkdown(rendered), nil
+}
+
 func getStateIcon(state string) string {
 	switch strings.ToLower(state) {
 	case "on":
@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+// maxChangedStatesShown limits the number of changed states listed in a reply
+const maxChangedStatesShown = 10
+
+// handleCall calls an arbitrary service:
+//
+//	/call light.turn_on light.kitchen brightness_pct=50
+//	/call notify.mobile_app_phone message="Power is back"
+//	/call light.turn_off area:bedroom
+//	/call climate.set_temperature climate.living {"temperature": 21}
+func (b *Bot) handleCall(ctx context.Context, args string) (string, error) {
+	domain, service, target, data, err := parseServiceCall(args)
+	if err != nil {
+		return "", err
+	}
+
+	if err := b.checkTarget(ctx, target); err != nil {
+		return "", err
+	}
+
+	changed, err := b.haClient.CallServiceWithData(ctx, domain, service, target, data)
+	if err != nil {
+		return "", err
+	}
+	// Any entity may have been affected (areas, devices, scripts)
+	b.states.Invalidate()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✅ Called `%s.%s`", domain, service))
+
+	if len(changed) == 0 {
+		return sb.String(), nil
+	}
+
+	sb.WriteString(fmt.Sprintf("\n\nChanged states (%d):\n", len(changed)))
+	for i, e := range changed {
+		if i >= maxChangedStatesShown {
+			sb.WriteString(fmt.Sprintf("... and %d more\n", len(changed)-maxChangedStatesShown))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s `%s`: %s\n", getStateIcon(e.State), e.EntityID, e.State))
+	}
+
+	return sb.String(), nil
+}
+
+// parseServiceCall parses "/call" arguments: "<domain>.<service> [targets...] [key=value...] [{json}]".
+// Targets are entity IDs, "area:<id>" or "device:<id>".
+func parseServiceCall(args string) (domain, service string, target homeassistant.ServiceTarget, data map[string]interface{}, err error) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return "", "", target, nil, fmt.Errorf("please provide service: /call <domain>.<service> [entity_id] [key=value]")
+	}
+
+	// Trailing JSON object with service data
+	var jsonData string
+	if idx := jsonDataStart(args); idx >= 0 {
+		jsonData = args[idx:]
+		args = strings.TrimSpace(args[:idx])
+	}
+
+	tokens, err := splitArgs(args)
+	if err != nil {
+		return "", "", target, nil, err
+	}
+	if len(tokens) == 0 {
+		return "", "", target, nil, fmt.Errorf("please provide service: /call <domain>.<service>")
+	}
+
+	parts := strings.SplitN(tokens[0], ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", target, nil, fmt.Errorf("invalid service %q, expected <domain>.<service>", tokens[0])
+	}
+	domain, service = parts[0], parts[1]
+
+	data = make(map[string]interface{})
+	if jsonData != "" {
+		if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
+			return "", "", target, nil, fmt.Errorf("invalid service data JSON: %w", err)
+		}
+	}
+
+	for _, token := range tokens[1:] {
+		switch {
+		case strings.HasPrefix(token, "area:"):
+			target.AreaIDs = append(target.AreaIDs, strings.TrimPrefix(token, "area:"))
+		case strings.HasPrefix(token, "device:"):
+			target.DeviceIDs = append(target.DeviceIDs, strings.TrimPrefix(token, "device:"))
+		case strings.Contains(token, "="):
+			kv := strings.SplitN(token, "=", 2)
+			data[kv[0]] = parseValue(kv[1])
+		case strings.Contains(token, "."):
+			target.EntityIDs = append(target.EntityIDs, token)
+		default:
+			return "", "", target, nil, fmt.Errorf("unexpected argument %q", token)
+		}
+	}
+
+	if err := moveDataTarget(data, &target); err != nil {
+		return "", "", target, nil, err
+	}
+
+	return domain, service, target, data, nil
+}
+
+// moveDataTarget moves target fields given as service data ("entity_id=lock.door"
+// or in the JSON object) into the target, so they are checked like any other target.
+// Target fields the bot can't check are rejected.
+func moveDataTarget(data map[string]interface{}, target *homeassistant.ServiceTarget) error {
+	fields := []struct {
+		key string
+		ids *[]string
+	}{
+		{"entity_id", &target.EntityIDs},
+		{"area_id", &target.AreaIDs},
+		{"device_id", &target.DeviceIDs},
+	}
+
+	for _, field := range fields {
+		value, ok := data[field.key]
+		if !ok {
+			continue
+		}
+		delete(data, field.key)
+
+		ids, err := targetIDs(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", field.key, err)
+		}
+		*field.ids = append(*field.ids, ids...)
+	}
+
+	for _, key := range []string{"target", "floor_id", "label_id"} {
+		if _, ok := data[key]; ok {
+			return fmt.Errorf("%s is not supported, use entity IDs, area:<id> or device:<id> targets", key)
+		}
+	}
+	return nil
+}
+
+// targetIDs reads a target field value: a string, comma-separated like Home
+// Assistant accepts it, or a list of strings
+func targetIDs(value interface{}) ([]string, error) {
+	var ids []string
+	switch v := value.(type) {
+	case string:
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			id, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of IDs, got %v", item)
+			}
+			ids = append(ids, id)
+		}
+	default:
+		return nil, fmt.Errorf("expected an ID or a list of IDs, got %v", value)
+	}
+	return ids, nil
+}
+
+// parseValue interprets numbers, booleans, arrays and objects as JSON, anything else as string
+func parseValue(value string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err == nil {
+		return v
+	}
+	return value
+}
+
+// jsonDataStart returns the position of the trailing JSON object: the first
+// argument starting with "{" outside quotes, or -1. Braces inside values like
+// key="{x}" don't count.
+func jsonDataStart(s string) int {
+	inQuotes := false
+	atStart := true
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '{' && atStart && !inQuotes:
+			return i
+		}
+		atStart = unicode.IsSpace(r) && !inQuotes
+	}
+	return -1
+}
+
+// splitArgs splits arguments on whitespace, keeping double-quoted parts together
+func splitArgs(s string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	hasToken := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasToken = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if hasToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				hasToken = false
+			}
+		default:
+			current.WriteRune(r)
+			hasToken = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in arguments")
+	}
+	if hasToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
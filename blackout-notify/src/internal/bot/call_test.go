@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+func TestParseServiceCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		domain  string
+		service string
+		target  homeassistant.ServiceTarget
+		data    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "entity and key=value",
+			args:    "light.turn_on light.kitchen brightness_pct=50",
+			domain:  "light",
+			service: "turn_on",
+			target:  homeassistant.EntityTarget("light.kitchen"),
+			data:    map[string]interface{}{"brightness_pct": float64(50)},
+		},
+		{
+			name:    "quoted value",
+			args:    `notify.mobile_app_phone message="Power is back" title=Home`,
+			domain:  "notify",
+			service: "mobile_app_phone",
+			data:    map[string]interface{}{"message": "Power is back", "title": "Home"},
+		},
+		{
+			name:    "area and device targets",
+			args:    "light.turn_off area:bedroom device:abc123",
+			domain:  "light",
+			service: "turn_off",
+			target:  homeassistant.ServiceTarget{AreaIDs: []string{"bedroom"}, DeviceIDs: []string{"abc123"}},
+			data:    map[string]interface{}{},
+		},
+		{
+			name:    "JSON tail",
+			args:    `climate.set_temperature climate.living {"temperature": 21, "hvac_mode": "heat"}`,
+			domain:  "climate",
+			service: "set_temperature",
+			target:  homeassistant.EntityTarget("climate.living"),
+			data:    map[string]interface{}{"temperature": float64(21), "hvac_mode": "heat"},
+		},
+		{
+			name:    "key=value overrides JSON tail",
+			args:    `light.turn_on brightness_pct=20 {"brightness_pct": 80, "transition": 2}`,
+			domain:  "light",
+			service: "turn_on",
+			data:    map[string]interface{}{"brightness_pct": float64(20), "transition": float64(2)},
+		},
+		{
+			name:    "entity_id as key=value moves to target",
+			args:    "lock.unlock entity_id=lock.front_door",
+			domain:  "lock",
+			service: "unlock",
+			target:  homeassistant.EntityTarget("lock.front_door"),
+			data:    map[string]interface{}{},
+		},
+		{
+			name:    "target fields in JSON tail move to target",
+			args:    `lock.unlock lock.back_door {"entity_id": ["lock.front_door"], "area_id": "hall", "device_id": "a, b", "code": "1234"}`,
+			domain:  "lock",
+			service: "unlock",
+			target: homeassistant.ServiceTarget{
+				EntityIDs: []string{"lock.back_door", "lock.front_door"},
+				AreaIDs:   []string{"hall"},
+				DeviceIDs: []string{"a", "b"},
+			},
+			data: map[string]interface{}{"code": "1234"},
+		},
+		{
+			name:    "braces in quoted value",
+			args:    `notify.phone message="{x} is {y}" title=Home`,
+			domain:  "notify",
+			service: "phone",
+			data:    map[string]interface{}{"message": "{x} is {y}", "title": "Home"},
+		},
+		{
+			name:    "braces inside value",
+			args:    `input_text.set_value input_text.note value={x}`,
+			domain:  "input_text",
+			service: "set_value",
+			target:  homeassistant.EntityTarget("input_text.note"),
+			data:    map[string]interface{}{"value": "{x}"},
+		},
+		{
+			name:    "JSON tail after quoted braces",
+			args:    `notify.phone message="{x}" {"title": "Home"}`,
+			domain:  "notify",
+			service: "phone",
+			data:    map[string]interface{}{"message": "{x}", "title": "Home"},
+		},
+		{name: "empty", args: "  ", wantErr: "please provide service"},
+		{name: "no service", args: "light", wantErr: "invalid service"},
+		{name: "unterminated quote", args: `notify.phone message="Power`, wantErr: "unterminated quote"},
+		{name: "invalid JSON tail", args: `light.turn_on {"brightness": }`, wantErr: "invalid service data JSON"},
+		{name: "unexpected argument", args: "light.turn_on kitchen", wantErr: "unexpected argument"},
+		{name: "invalid entity_id", args: `lock.unlock {"entity_id": 5}`, wantErr: "invalid entity_id"},
+		{name: "nested target", args: `lock.unlock {"target": {"entity_id": "lock.front_door"}}`, wantErr: "target is not supported"},
+		{name: "label target", args: "lock.unlock label_id=doors", wantErr: "label_id is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, service, target, data, err := parseServiceCall(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseServiceCall() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseServiceCall() error = %v", err)
+			}
+
+			if domain != tt.domain || service != tt.service {
+				t.Errorf("service = %s.%s, want %s.%s", domain, service, tt.domain, tt.service)
+			}
+			if !reflect.DeepEqual(target, tt.target) {
+				t.Errorf("target = %+v, want %+v", target, tt.target)
+			}
+			if !reflect.DeepEqual(data, tt.data) {
+				t.Errorf("data = %v, want %v", data, tt.data)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    []string
+		wantErr bool
+	}{
+		{args: "a  b\tc", want: []string{"a", "b", "c"}},
+		{args: `message="Power is back" x`, want: []string{"message=Power is back", "x"}},
+		{args: `"" b`, want: []string{"", "b"}},
+		{args: `a"b c"d`, want: []string{"ab cd"}},
+		{args: "", want: nil},
+		{args: `"open`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCallTarget(t *testing.T) {
+	restricted := &Bot{config: &config.Config{EntityAllow: []string{"light.*"}, EntityDeny: []string{"lock.*"}}}
+	unrestricted := &Bot{config: &config.Config{}}
+
+	tests := []struct {
+		name    string
+		bot     *Bot
+		args    string
+		wantErr bool
+	}{
+		{name: "allowed entity", bot: restricted, args: "light.turn_on light.kitchen"},
+		{name: "denied entity", bot: restricted, args: "lock.unlock lock.front_door", wantErr: true},
+		{name: "denied entity as key=value", bot: restricted, args: "lock.unlock entity_id=lock.front_door", wantErr: true},
+		{name: "denied entity in JSON tail", bot: restricted, args: `lock.unlock light.kitchen {"entity_id": "lock.front_door"}`, wantErr: true},
+		{name: "all entities", bot: restricted, args: "light.turn_off entity_id=all", wantErr: true},
+		{name: "no target", bot: restricted, args: "lock.unlock code=1234", wantErr: true},
+		// Areas can't be expanded without the WebSocket connection
+		{name: "area as key=value", bot: restricted, args: "light.turn_on area_id=hall", wantErr: true},
+		{name: "no target without patterns", bot: unrestricted, args: "notify.phone message=hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, target, _, err := parseServiceCall(tt.args)
+			if err != nil {
+				t.Fatalf("parseServiceCall() error = %v", err)
+			}
+			if err := tt.bot.checkTarget(context.Background(), target); (err != nil) != tt.wantErr {
+				t.Errorf("checkTarget(%+v) error = %v, wantErr %v", target, err, tt.wantErr)
+			}
+		})
+	}
+}
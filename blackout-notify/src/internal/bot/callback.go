@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/audit"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+)
+
+// dispatchCallback checks access to an inline button press and handles it.
+// Callback data has the form "<action>:<payload>".
+func (b *Bot) dispatchCallback(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		b.answerCallback(query.ID, "")
+		return
+	}
+
+	role := b.config.MemberRole(query.Message.Chat.ID, userID(query.From))
+	if role == "" {
+		logger.Warn("Unauthorized callback from chat ID: %d, user ID: %d", query.Message.Chat.ID, userID(query.From))
+		b.recordCallback(query, time.Now(), audit.ResultDenied, nil)
+		b.answerCallback(query.ID, "⛔ Access denied")
+		return
+	}
+
+	action, _, _ := strings.Cut(query.Data, ":")
+	if required := requiredRole(callbackRoles, action); !config.RoleAtLeast(role, required) {
+		logger.Warn("User %d in chat %d (%s) denied callback %s, requires %s", userID(query.From), query.Message.Chat.ID, role, action, required)
+		b.recordCallback(query, time.Now(), audit.ResultDenied, nil)
+		b.answerCallback(query.ID, "⛔ Requires the "+required+" role")
+		return
+	}
+
+	go b.handleCallback(ctx, query)
+}
+
+// handleCallback routes a button press by its action. Handlers may edit the
+// message the button belongs to and return a short notice shown to the user.
+func (b *Bot) handleCallback(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	action, payload, _ := strings.Cut(query.Data, ":")
+	logger.Debug("Received callback: %s from chat %d", query.Data, query.Message.Chat.ID)
+	started := time.Now()
+
+	var notice string
+	var err error
+	switch action {
+	case callbackHistory:
+		notice, err = b.handleHistoryCallback(ctx, query, payload)
+	case callbackService:
+		notice, err = b.handleServiceCallback(ctx, query, payload)
+	case callbackArea:
+		notice, err = b.handleAreaCallback(ctx, query, payload)
+	case callbackPick:
+		notice, err = b.handlePickCallback(ctx, query, payload)
+	case callbackConfirm:
+		notice, err = b.handleConfirmCallback(ctx, query, payload)
+	case callbackState:
+		notice, err = b.handleStateCallback(ctx, query, payload)
+	case notifications.CallbackPause:
+		notice, err = b.handlePauseCallback(ctx, query, payload)
+	default:
+		b.answerCallback(query.ID, "Unknown action")
+		return
+	}
+
+	// Confirmations are recorded with the command they run
+	if action != callbackConfirm {
+		result := audit.ResultOK
+		if err != nil {
+			result = audit.ResultError
+		}
+		b.recordCallback(query, started, result, err)
+	}
+
+	if err != nil {
+		logger.Error("Callback %s failed: %v", action, err)
+		b.answerCallback(query.ID, "❌ "+err.Error())
+		return
+	}
+	b.answerCallback(query.ID, notice)
+}
+
+// recordCallback writes an audit entry for a button press
+func (b *Bot) recordCallback(query *tgbotapi.CallbackQuery, started time.Time, result string, err error) {
+	entry := audit.Entry{
+		Time:     started,
+		ChatID:   query.Message.Chat.ID,
+		UserID:   userID(query.From),
+		Username: userName(query.From),
+		Command:  auditButton,
+		Args:     query.Data,
+		Result:   result,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	b.record(entry)
+}
+
+// answerCallback stops the button spinner, optionally showing a short notice
+func (b *Bot) answerCallback(queryID, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(queryID, text)); err != nil {
+		logger.Error("Failed to answer callback: %v", err)
+	}
+}
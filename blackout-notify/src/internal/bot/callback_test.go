@@ -0,0 +1,348 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/gorilla/websocket"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+const (
+	viewerID   = 1
+	operatorID = 2
+	strangerID = 3
+	adminID    = 4
+)
+
+// telegramRequest is a Bot API call received by the fake Telegram server
+type telegramRequest struct {
+	method string
+	params url.Values
+}
+
+// newTelegramAPI creates a Bot API client talking to a fake Telegram server
+// that reports every call on the returned channel
+func newTelegramAPI(t *testing.T) (*tgbotapi.BotAPI, <-chan telegramRequest) {
+	requests := make(chan telegramRequest, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse Bot API request: %v", err)
+		}
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		switch method {
+		case "getMe":
+			w.Write([]byte(`{"ok": true, "result": {"id": 42, "is_bot": true, "first_name": "Bot", "username": "test_bot"}}`))
+			return
+		case "answerCallbackQuery":
+			w.Write([]byte(`{"ok": true, "result": true}`))
+		default:
+			w.Write([]byte(`{"ok": true, "result": {"message_id": 10, "date": 0, "chat": {"id": 1, "type": "private"}}}`))
+		}
+		requests <- telegramRequest{method: method, params: r.PostForm}
+	}))
+	t.Cleanup(server.Close)
+
+	api, err := tgbotapi.NewBotAPIWithClient("test_token", server.URL+"/bot%s/%s", server.Client())
+	if err != nil {
+		t.Fatalf("NewBotAPIWithClient() error = %v", err)
+	}
+	return api, requests
+}
+
+// nextRequest returns the next Bot API call with the given method, or any call if empty
+func nextRequest(t *testing.T, requests <-chan telegramRequest, method string) telegramRequest {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case req := <-requests:
+			if method == "" || req.method == method {
+				return req
+			}
+		case <-timeout:
+			t.Fatalf("No %s request", method)
+			return telegramRequest{}
+		}
+	}
+}
+
+// newHAServer serves the given states and records the called services
+func newHAServer(t *testing.T, entities []homeassistant.Entity) (*homeassistant.Client, func() []string) {
+	var mu sync.Mutex
+	var services []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/states":
+			json.NewEncoder(w).Encode(entities)
+		case strings.HasPrefix(r.URL.Path, "/states/"):
+			for _, e := range entities {
+				if e.EntityID == strings.TrimPrefix(r.URL.Path, "/states/") {
+					json.NewEncoder(w).Encode(e)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/template":
+			w.Write([]byte("ups_battery: *85*%"))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/services/"):
+			mu.Lock()
+			services = append(services, strings.TrimPrefix(r.URL.Path, "/services/"))
+			mu.Unlock()
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	called := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), services...)
+	}
+	return homeassistant.NewClient(server.URL, "test_token"), called
+}
+
+// newRegistryWS connects a WebSocket client to a fake Home Assistant serving reg
+func newRegistryWS(t *testing.T, reg *homeassistant.Registry) *homeassistant.WSClient {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteJSON(map[string]interface{}{"type": homeassistant.MsgTypeAuthRequired})
+		var auth map[string]interface{}
+		if err := conn.ReadJSON(&auth); err != nil {
+			return
+		}
+		conn.WriteJSON(map[string]interface{}{"type": homeassistant.MsgTypeAuthOK})
+
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+
+			var result interface{}
+			switch msg["type"] {
+			case homeassistant.MsgTypeAreaRegistryList:
+				result = reg.Areas
+			case homeassistant.MsgTypeDeviceRegistryList:
+				result = reg.Devices
+			case homeassistant.MsgTypeEntityRegistryList:
+				result = reg.Entities
+			}
+			conn.WriteJSON(map[string]interface{}{"id": msg["id"], "type": homeassistant.MsgTypeResult, "success": true, "result": result})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ws := homeassistant.NewWSClient(server.URL+"/api", "test_token")
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := ws.Connect(ctx); err != nil {
+		cancel()
+		t.Fatalf("Connect() error = %v", err)
+	}
+	go ws.Listen(ctx)
+	t.Cleanup(func() {
+		cancel()
+		ws.Close()
+	})
+	return ws
+}
+
+// newCallbackBot creates a bot with a viewer, an operator and an admin, backed by fake
+// Telegram and Home Assistant servers
+func newCallbackBot(t *testing.T) (*Bot, <-chan telegramRequest, func() []string) {
+	api, requests := newTelegramAPI(t)
+	client, services := newHAServer(t, []homeassistant.Entity{
+		entity("light.kitchen", "Kitchen Light"),
+		entity("light.hall", "Hall Light"),
+		entity("switch.kettle", "Kettle"),
+	})
+	ws := newRegistryWS(t, &homeassistant.Registry{
+		Areas: []homeassistant.Area{{AreaID: "kitchen", Name: "Kitchen"}},
+		Entities: []homeassistant.RegistryEntry{
+			{EntityID: "light.kitchen", AreaID: "kitchen"},
+			{EntityID: "switch.kettle", AreaID: "kitchen"},
+		},
+	})
+
+	cfg := &config.Config{BotRoles: map[int64]string{
+		viewerID:   config.RoleViewer,
+		operatorID: config.RoleOperator,
+		adminID:    config.RoleAdmin,
+	}}
+	b := &Bot{
+		api:      api,
+		config:   cfg,
+		haClient: client,
+		ws:       ws,
+		states:   homeassistant.NewStateCache(client, ws, time.Minute),
+		pending:  make(map[int]*pendingAction),
+	}
+	return b, requests, services
+}
+
+// callbackQuery creates a button press by the user in their private chat
+func callbackQuery(user int64, data string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		ID:      "query",
+		From:    &tgbotapi.User{ID: user, UserName: "user"},
+		Message: &tgbotapi.Message{MessageID: 10, Chat: &tgbotapi.Chat{ID: user}},
+		Data:    data,
+	}
+}
+
+// callbackData returns the callback data of the buttons in a reply_markup parameter
+func callbackData(t *testing.T, markup string) []string {
+	if markup == "" {
+		return nil
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(markup), &keyboard); err != nil {
+		t.Fatalf("Invalid reply_markup %q: %v", markup, err)
+	}
+
+	var data []string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			data = append(data, *button.CallbackData)
+		}
+	}
+	return data
+}
+
+func TestDispatchCallbackRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     int64
+		data     string
+		answer   string
+		services []string
+	}{
+		{name: "stranger denied", user: strangerID, data: "state:light.kitchen", answer: "⛔ Access denied"},
+		{name: "viewer refreshes state", user: viewerID, data: "state:light.kitchen"},
+		{name: "viewer denied service", user: viewerID, data: "svc:on:light.kitchen", answer: "⛔ Requires the operator role"},
+		{name: "viewer denied pause", user: viewerID, data: "pause:1h", answer: "⛔ Requires the operator role"},
+		{name: "operator runs service", user: operatorID, data: "svc:on:light.kitchen", answer: "✅ Turned ON: light.kitchen", services: []string{"light/turn_on"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, requests, services := newCallbackBot(t)
+
+			b.dispatchCallback(context.Background(), callbackQuery(tt.user, tt.data))
+
+			answer := nextRequest(t, requests, "answerCallbackQuery")
+			if got := answer.params.Get("text"); got != tt.answer {
+				t.Errorf("answer = %q, want %q", got, tt.answer)
+			}
+			if got := services(); strings.Join(got, ",") != strings.Join(tt.services, ",") {
+				t.Errorf("services called = %v, want %v", got, tt.services)
+			}
+		})
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		answer   string
+		services []string
+		text     string   // Part of the edited message, empty if not edited
+		buttons  []string // Callback data of its first row
+	}{
+		{
+			name:    "state refresh",
+			data:    "state:light.kitchen",
+			text:    "State: `off`",
+			buttons: []string{"state:light.kitchen"},
+		},
+		{
+			name:     "service from domain listing",
+			data:     "svc:toggle:light.hall",
+			answer:   "✅ Toggled: light.hall",
+			services: []string{"light/toggle"},
+			text:     "Domain: `light`",
+			buttons:  []string{"svc:toggle:light.hall", "svc:on:light.hall", "svc:off:light.hall"},
+		},
+		{
+			name:     "service from area listing",
+			data:     "svc:off:switch.kettle:kitchen",
+			answer:   "✅ Turned OFF: switch.kettle",
+			services: []string{"switch/turn_off"},
+			text:     "🏠 Kitchen",
+			buttons:  []string{"svc:toggle:light.kitchen:kitchen", "svc:on:light.kitchen:kitchen", "svc:off:light.kitchen:kitchen"},
+		},
+		{
+			name:    "area",
+			data:    "area:kitchen",
+			text:    "🏠 Kitchen",
+			buttons: []string{"svc:toggle:light.kitchen:kitchen", "svc:on:light.kitchen:kitchen", "svc:off:light.kitchen:kitchen"},
+		},
+		{name: "removed area", data: "area:garage", answer: "❌ area garage no longer exists"},
+		{name: "pause without power monitoring", data: "pause:1h", answer: "❌ power monitoring is not configured"},
+		{name: "unknown action", data: "bogus:1", answer: "Unknown action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, requests, services := newCallbackBot(t)
+
+			b.handleCallback(context.Background(), callbackQuery(operatorID, tt.data))
+
+			// The message is edited before the button is answered
+			var edit *telegramRequest
+			for answered := false; !answered; {
+				req := nextRequest(t, requests, "")
+				switch req.method {
+				case "editMessageText":
+					edit = &req
+				case "answerCallbackQuery":
+					answered = true
+					if got := req.params.Get("text"); got != tt.answer {
+						t.Errorf("answer = %q, want %q", got, tt.answer)
+					}
+				}
+			}
+
+			if got := services(); strings.Join(got, ",") != strings.Join(tt.services, ",") {
+				t.Errorf("services called = %v, want %v", got, tt.services)
+			}
+
+			if tt.text == "" {
+				if edit != nil {
+					t.Errorf("message edited to %q, want no edit", edit.params.Get("text"))
+				}
+				return
+			}
+			if edit == nil {
+				t.Fatal("message not edited")
+			}
+			if text := edit.params.Get("text"); !strings.Contains(text, tt.text) {
+				t.Errorf("edited text = %q, want it to contain %q", text, tt.text)
+			}
+			if got := callbackData(t, edit.params.Get("reply_markup")); len(got) < len(tt.buttons) || strings.Join(got[:len(tt.buttons)], ",") != strings.Join(tt.buttons, ",") {
+				t.Errorf("edited buttons = %v, want first row %v", got, tt.buttons)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/audit"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+const (
+	// callbackConfirm prefixes callback data of confirmation buttons: "confirm:<yes|no>:<id>"
+	callbackConfirm = "confirm"
+
+	// defaultConfirmTimeout applies when confirm_timeout is not positive
+	defaultConfirmTimeout = time.Minute
+)
+
+// pendingAction is a service call on a sensitive entity waiting for confirmation
+type pendingAction struct {
+	command   string // Command name, e.g. "turn_off"
+	args      string
+	chatID    int64
+	messageID int
+	userID    int64
+	user      string
+	run       func(ctx context.Context) (string, error)
+	timer     *time.Timer
+}
+
+// confirmable returns how to run a command that must be confirmed first, or nil
+// if it can run right away. Turning off, toggling and /call are confirmed when
+// they target an entity matching confirm_entities, also through an area.
+// Commands that would be refused anyway run right away to report the error.
+func (b *Bot) confirmable(ctx context.Context, command, args string) func(ctx context.Context) (string, error) {
+	switch command {
+	case "turn_off", "off":
+		if b.entityNeedsConfirmation(ctx, args) {
+			return func(ctx context.Context) (string, error) { return b.handleTurnOff(ctx, args) }
+		}
+	case "toggle":
+		if b.entityNeedsConfirmation(ctx, args) {
+			return func(ctx context.Context) (string, error) { return b.handleToggle(ctx, args) }
+		}
+	case "call":
+		_, _, target, _, err := parseServiceCall(args)
+		if err == nil && b.checkTarget(ctx, target) == nil && b.targetNeedsConfirmation(ctx, target) {
+			return func(ctx context.Context) (string, error) { return b.handleCall(ctx, args) }
+		}
+	}
+	return nil
+}
+
+// entityNeedsConfirmation checks the argument of /turn_off or /toggle: an
+// entity ID or "area:<name>"
+func (b *Bot) entityNeedsConfirmation(ctx context.Context, arg string) bool {
+	arg = strings.TrimSpace(arg)
+	if area, ok := strings.CutPrefix(arg, areaPrefix); ok {
+		return b.areaNeedsConfirmation(ctx, area)
+	}
+	return b.config.NeedsConfirmation(arg) && b.checkEntity(arg) == nil
+}
+
+// targetNeedsConfirmation checks the entities of a service call target, with
+// areas and devices expanded. If the registries can't be read, area and device
+// targets are confirmed while patterns are set, and so are calls on all entities.
+// Target fields given as service data are part of the target, see parseServiceCall.
+func (b *Bot) targetNeedsConfirmation(ctx context.Context, target homeassistant.ServiceTarget) bool {
+	if len(b.config.ConfirmEntities) == 0 {
+		return false
+	}
+
+	if target.TargetsAll() {
+		return true
+	}
+
+	ids := target.EntityIDs
+	if len(target.AreaIDs) > 0 || len(target.DeviceIDs) > 0 {
+		reg, err := b.registry(ctx)
+		if err != nil {
+			return true
+		}
+		ids = reg.ExpandTarget(target)
+	}
+
+	for _, id := range ids {
+		if b.config.NeedsConfirmation(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestConfirmation asks the user to confirm a command with inline buttons.
+// The command runs only when the same user presses Confirm before the timeout.
+func (b *Bot) requestConfirmation(chatID int64, from *tgbotapi.User, command, args string, run func(ctx context.Context) (string, error)) {
+	timeout := b.confirmTimeout()
+
+	b.pendingMu.Lock()
+	b.nextPendingID++
+	id := b.nextPendingID
+	b.pendingMu.Unlock()
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Confirm `%s`?\n\n_Expires in %v_", commandText(command, args), timeout))
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", fmt.Sprintf("%s:yes:%d", callbackConfirm, id)),
+		tgbotapi.NewInlineKeyboardButtonData("✖️ Cancel", fmt.Sprintf("%s:no:%d", callbackConfirm, id)),
+	))
+
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		logger.Error("Failed to send confirmation: %v", err)
+		return
+	}
+
+	action := &pendingAction{
+		command:   command,
+		args:      args,
+		chatID:    chatID,
+		messageID: sent.MessageID,
+		userID:    userID(from),
+		user:      userName(from),
+		run:       run,
+	}
+
+	b.pendingMu.Lock()
+	b.pending[id] = action
+	action.timer = time.AfterFunc(timeout, func() { b.expireConfirmation(id) })
+	b.pendingMu.Unlock()
+}
+
+// handleConfirmCallback runs or cancels a pending command; payload is "<yes|no>:<id>"
+func (b *Bot) handleConfirmCallback(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) (string, error) {
+	answer, idArg, _ := strings.Cut(payload, ":")
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return "", fmt.Errorf("invalid confirmation %q", idArg)
+	}
+
+	b.pendingMu.Lock()
+	action := b.pending[id]
+	if action != nil && action.userID != userID(query.From) {
+		b.pendingMu.Unlock()
+		return "", fmt.Errorf("only %s can confirm this", action.user)
+	}
+	b.pendingMu.Unlock()
+
+	// Another press or the timeout may have taken it meanwhile
+	if action = b.takePending(id); action == nil {
+		b.editMessage(query.Message.Chat.ID, query.Message.MessageID, "⌛ This confirmation has expired.", nil)
+		return "", nil
+	}
+
+	if answer != "yes" {
+		b.recordConfirmation(action, time.Now(), audit.ResultCancelled, nil)
+		b.editMessage(action.chatID, action.messageID, fmt.Sprintf("✖️ Cancelled `%s`", commandText(action.command, action.args)), nil)
+		return "Cancelled", nil
+	}
+
+	started := time.Now()
+	response, err := action.run(ctx)
+	if err != nil {
+		b.recordConfirmation(action, started, audit.ResultError, err)
+		b.editMessage(action.chatID, action.messageID, fmt.Sprintf("❌ Error: %s", err.Error()), nil)
+		return "", err
+	}
+
+	b.recordConfirmation(action, started, audit.ResultOK, nil)
+	b.editMessage(action.chatID, action.messageID, response, nil)
+	return "", nil
+}
+
+// expireConfirmation drops a pending command nobody confirmed in time
+func (b *Bot) expireConfirmation(id int) {
+	action := b.takePending(id)
+	if action == nil {
+		return
+	}
+
+	b.recordConfirmation(action, time.Now(), audit.ResultExpired, nil)
+	b.editMessage(action.chatID, action.messageID, fmt.Sprintf("⌛ Not confirmed in time: `%s`", commandText(action.command, action.args)), nil)
+}
+
+// takePending removes a pending command and stops its timeout
+func (b *Bot) takePending(id int) *pendingAction {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+
+	action := b.pending[id]
+	if action == nil {
+		return nil
+	}
+	delete(b.pending, id)
+	action.timer.Stop()
+	return action
+}
+
+// recordConfirmation writes an audit entry for the outcome of a pending command
+func (b *Bot) recordConfirmation(action *pendingAction, started time.Time, result string, err error) {
+	entry := audit.Entry{
+		Time:     started,
+		ChatID:   action.chatID,
+		UserID:   action.userID,
+		Username: action.user,
+		Command:  action.command,
+		Args:     action.args,
+		Result:   result,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	b.record(entry)
+}
+
+// commandText returns a command as typed, e.g. "/turn_off lock.front_door"
+func commandText(command, args string) string {
+	return strings.TrimSpace("/" + command + " " + args)
+}
+
+// confirmTimeout returns how long a confirmation stays valid
+func (b *Bot) confirmTimeout() time.Duration {
+	if b.config.ConfirmTimeout <= 0 {
+		return defaultConfirmTimeout
+	}
+	return time.Duration(b.config.ConfirmTimeout) * time.Second
+}
@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+)
+
+func TestConfirmableCall(t *testing.T) {
+	b := &Bot{config: &config.Config{ConfirmEntities: []string{"lock.*", "alarm_control_panel.*"}}}
+
+	tests := []struct {
+		args string
+		want bool
+	}{
+		{"lock.unlock lock.front_door", true},
+		{"lock.unlock entity_id=lock.front_door", true},
+		{`lock.unlock {"entity_id": ["lock.front_door"], "code": "1234"}`, true},
+		{`light.turn_on light.kitchen {"entity_id": "lock.front_door"}`, true},
+		{"lock.unlock entity_id=all", true},
+		{"light.turn_on light.kitchen brightness_pct=50", false},
+		{"light.turn_on entity_id=light.kitchen", false},
+		{"notify.phone message=hi", false},
+		// Invalid calls run right away to report the error
+		{"lock.unlock label_id=doors", false},
+	}
+
+	for _, tt := range tests {
+		if got := b.confirmable(context.Background(), "call", tt.args) != nil; got != tt.want {
+			t.Errorf("confirmable(call %s) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+)
+
+const (
+	// historyPageSize is the number of outages listed per message
+	historyPageSize = 10
+
+	// maxHistoryDays matches the outage journal retention
+	maxHistoryDays = 90
+
+	// callbackHistory prefixes callback data of history page buttons
+	callbackHistory = "history"
+)
+
+// historyPeriod is the reporting period of /history
+type historyPeriod struct {
+	key      string // Canonical form used in callback data: "today", "yesterday" or "<N>d"
+	title    string
+	from, to time.Time
+}
+
+// parseHistoryPeriod parses the /history argument: today, yesterday, week,
+// month or a number of days ("3", "3d"). The default is 7 days.
+func parseHistoryPeriod(arg string, now time.Time) (historyPeriod, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "today", "сьогодні":
+		return historyPeriod{key: "today", title: "сьогодні", from: midnight, to: now}, nil
+	case "yesterday", "вчора":
+		return historyPeriod{key: "yesterday", title: "вчора", from: midnight.AddDate(0, 0, -1), to: midnight}, nil
+	case "", "week", "тиждень":
+		arg = "7"
+	case "month", "місяць":
+		arg = "30"
+	}
+
+	days, err := strconv.Atoi(strings.TrimRight(strings.ToLower(strings.TrimSpace(arg)), "dд"))
+	if err != nil || days < 1 || days > maxHistoryDays {
+		return historyPeriod{}, fmt.Errorf("unknown period %q, use today, yesterday, week, month or 1-%d days: /history 3d", arg, maxHistoryDays)
+	}
+
+	return historyPeriod{
+		key:   fmt.Sprintf("%dd", days),
+		title: fmt.Sprintf("%d %s", days, pluralDays(days)),
+		from:  midnight.AddDate(0, 0, -(days - 1)),
+		to:    now,
+	}, nil
+}
+
+// pluralDays returns the Ukrainian word for "days" agreeing with n
+func pluralDays(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return "день"
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return "дні"
+	default:
+		return "днів"
+	}
+}
+
+// handleHistory lists outages of a period with totals
+func (b *Bot) handleHistory(ctx context.Context, args string) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	if b.watcher == nil || b.watcher.Journal() == nil {
+		return "Power monitoring is not configured. Set watched_entity_id or infer_power_from_connection.", nil, nil
+	}
+
+	period, err := parseHistoryPeriod(args, time.Now().In(b.notifSvc.Location()))
+	if err != nil {
+		return "", nil, err
+	}
+
+	text, keyboard := b.renderHistory(ctx, period, 0)
+	return text, keyboard, nil
+}
+
+// handleHistoryCallback switches pages of a /history message; payload is "<period>:<page>"
+func (b *Bot) handleHistoryCallback(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) (string, error) {
+	if b.watcher == nil || b.watcher.Journal() == nil {
+		return "", fmt.Errorf("power monitoring is not configured")
+	}
+
+	key, pageArg, _ := strings.Cut(payload, ":")
+	page, err := strconv.Atoi(pageArg)
+	if err != nil {
+		return "", fmt.Errorf("invalid page %q", pageArg)
+	}
+
+	period, err := parseHistoryPeriod(key, time.Now().In(b.notifSvc.Location()))
+	if err != nil {
+		return "", err
+	}
+
+	text, keyboard := b.renderHistory(ctx, period, page)
+	b.editMessage(query.Message.Chat.ID, query.Message.MessageID, text, keyboard)
+	return "", nil
+}
+
+// renderHistory formats one page of outages, newest first, with period totals
+func (b *Bot) renderHistory(ctx context.Context, period historyPeriod, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	loc := b.notifSvc.Location()
+	now := time.Now().In(loc)
+	journal := b.watcher.Journal()
+
+	list := journal.Between(period.from, period.to)
+	stats := journal.Stats(period.from, period.to, now)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📊 *Відключення за %s*\n", period.title))
+	sb.WriteString(fmt.Sprintf("_%s – %s_\n\n", period.from.Format("02.01 15:04"), period.to.In(loc).Format("02.01 15:04")))
+
+	if stats.Count == 0 {
+		sb.WriteString("Відключень не було ✨")
+		return sb.String(), nil
+	}
+
+	sb.WriteString(fmt.Sprintf("Без світла: *%s*", notifications.FormatDuration(stats.Total)))
+	if length := period.to.Sub(period.from); length > 0 {
+		sb.WriteString(fmt.Sprintf(" (%.0f%%)", float64(stats.Total)/float64(length)*100))
+	}
+	sb.WriteString(fmt.Sprintf("\nВідключень: *%d*, найдовше: *%s*\n", stats.Count, notifications.FormatDuration(stats.Longest)))
+
+	scheduled, ok, err := b.notifSvc.ScheduledOffTime(ctx, period.from, period.to)
+	if err != nil {
+		logger.Warn("Failed to read scheduled outages: %v", err)
+	} else if ok {
+		sb.WriteString(fmt.Sprintf("За графіком: *%s* (%s)\n", notifications.FormatDuration(scheduled), compareWithSchedule(stats.Total, scheduled)))
+	}
+
+	pages := (len(list) + historyPageSize - 1) / historyPageSize
+	page = min(max(page, 0), pages-1)
+
+	// Newest first
+	sb.WriteString("\n")
+	for i := len(list) - 1 - page*historyPageSize; i >= 0 && i >= len(list)-(page+1)*historyPageSize; i-- {
+		o := list[i]
+		start := o.Start.In(loc)
+		if o.End == nil {
+			sb.WriteString(fmt.Sprintf("`%s–…` %s _(триває)_\n", start.Format("02.01 15:04"), notifications.FormatDuration(o.Duration(now))))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("`%s–%s` %s\n", start.Format("02.01 15:04"), o.End.In(loc).Format("15:04"), notifications.FormatDuration(o.Duration(now))))
+	}
+
+	if pages == 1 {
+		return sb.String(), nil
+	}
+
+	sb.WriteString(fmt.Sprintf("\n_Сторінка %d/%d_", page+1, pages))
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("◀️ Новіші", fmt.Sprintf("%s:%s:%d", callbackHistory, period.key, page-1)))
+	}
+	if page < pages-1 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("Старіші ▶️", fmt.Sprintf("%s:%s:%d", callbackHistory, period.key, page+1)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons)
+	return sb.String(), &keyboard
+}
+
+// compareWithSchedule describes how actual time without power differs from the schedule
+func compareWithSchedule(actual, scheduled time.Duration) string {
+	diff := (actual - scheduled).Round(time.Minute)
+	switch {
+	case diff > 0:
+		return fmt.Sprintf("фактично на %s більше", notifications.FormatDuration(diff))
+	case diff < 0:
+		return fmt.Sprintf("фактично на %s менше", notifications.FormatDuration(-diff))
+	default:
+		return "як за графіком"
+	}
+}
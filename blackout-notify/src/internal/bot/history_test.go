@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHistoryPeriod(t *testing.T) {
+	loc := time.FixedZone("EET", 2*60*60)
+	now := time.Date(2026, 3, 15, 14, 30, 0, 0, loc)
+	midnight := time.Date(2026, 3, 15, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		arg     string
+		key     string
+		title   string
+		from    time.Time
+		to      time.Time
+		wantErr bool
+	}{
+		{arg: "", key: "7d", title: "7 днів", from: midnight.AddDate(0, 0, -6), to: now},
+		{arg: "today", key: "today", title: "сьогодні", from: midnight, to: now},
+		{arg: "Вчора", key: "yesterday", title: "вчора", from: midnight.AddDate(0, 0, -1), to: midnight},
+		{arg: "week", key: "7d", title: "7 днів", from: midnight.AddDate(0, 0, -6), to: now},
+		{arg: "місяць", key: "30d", title: "30 днів", from: midnight.AddDate(0, 0, -29), to: now},
+		{arg: "1", key: "1d", title: "1 день", from: midnight, to: now},
+		{arg: " 3D ", key: "3d", title: "3 дні", from: midnight.AddDate(0, 0, -2), to: now},
+		{arg: "90д", key: "90d", title: "90 днів", from: midnight.AddDate(0, 0, -89), to: now},
+		{arg: "0", wantErr: true},
+		{arg: "-3", wantErr: true},
+		{arg: "91", wantErr: true},
+		{arg: "fortnight", wantErr: true},
+		{arg: "3w", wantErr: true},
+		{arg: "d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseHistoryPeriod(tt.arg, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseHistoryPeriod(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got.key != tt.key || got.title != tt.title || !got.from.Equal(tt.from) || !got.to.Equal(tt.to) {
+			t.Errorf("parseHistoryPeriod(%q) = %s %q %v-%v, want %s %q %v-%v",
+				tt.arg, got.key, got.title, got.from, got.to, tt.key, tt.title, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+const (
+	// maxEntitiesShown limits the entities listed by /entities <domain>
+	maxEntitiesShown = 20
+
+	// maxCallbackData is the Telegram limit for callback data, in bytes
+	maxCallbackData = 64
+
+	// maxButtonLabel limits entity names on buttons, in characters
+	maxButtonLabel = 24
+
+	// Callback actions, see dispatchCallback
+	callbackService = "svc"   // "svc:<on|off|toggle>:<entity_id>[:<area_id>]"
+	callbackState   = "state" // "state:<entity_id>"
+)
+
+// controllableDomains are domains whose entities get on/off/toggle buttons
+var controllableDomains = map[string]bool{
+	"light":         true,
+	"switch":        true,
+	"fan":           true,
+	"input_boolean": true,
+	"automation":    true,
+	"siren":         true,
+	"humidifier":    true,
+}
+
+// callbackButton creates a button, or returns false if the data exceeds the Telegram limit
+func callbackButton(label string, parts ...string) (tgbotapi.InlineKeyboardButton, bool) {
+	data := strings.Join(parts, ":")
+	if len(data) > maxCallbackData {
+		return tgbotapi.InlineKeyboardButton{}, false
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(label, data), true
+}
+
+// entitiesKeyboard creates on/off/toggle buttons for controllable entities passing allowed.
+// areaID is the area being listed, empty for a domain listing.
+func entitiesKeyboard(entities []homeassistant.Entity, areaID string, allowed func(entityID string) bool) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, e := range entities {
+		if !controllableDomains[getDomain(e.EntityID)] || !allowed(e.EntityID) {
+			continue
+		}
+
+		toggle, ok := serviceButton(getStateIcon(e.State)+" "+entityLabel(e), "toggle", e.EntityID, areaID)
+		if !ok {
+			continue
+		}
+		on, _ := serviceButton("On", "on", e.EntityID, areaID)
+		off, _ := serviceButton("Off", "off", e.EntityID, areaID)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(toggle, on, off))
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// serviceButton creates an on/off/toggle button that refreshes the area listing
+// afterwards, or the domain listing if areaID is empty or doesn't fit
+func serviceButton(label, action, entityID, areaID string) (tgbotapi.InlineKeyboardButton, bool) {
+	if areaID != "" {
+		if button, ok := callbackButton(label, callbackService, action, entityID, areaID); ok {
+			return button, true
+		}
+	}
+	return callbackButton(label, callbackService, action, entityID)
+}
+
+// stateKeyboard creates a refresh button for a /state reply
+func stateKeyboard(entityID string) *tgbotapi.InlineKeyboardMarkup {
+	entityID = strings.TrimSpace(entityID)
+	if entityID == "" {
+		return nil
+	}
+
+	refresh, ok := callbackButton("🔄 Refresh", callbackState, entityID)
+	if !ok {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(refresh))
+	return &keyboard
+}
+
+// entityLabel returns a short name of an entity for a button
+func entityLabel(e homeassistant.Entity) string {
+	name, _ := e.Attributes["friendly_name"].(string)
+	if name == "" {
+		_, objectID, _ := strings.Cut(e.EntityID, ".")
+		name = objectID
+	}
+
+	runes := []rune(name)
+	if len(runes) > maxButtonLabel {
+		return string(runes[:maxButtonLabel-1]) + "…"
+	}
+	return name
+}
+
+// getDomain returns the domain part of an entity ID
+func getDomain(entityID string) string {
+	return strings.SplitN(entityID, ".", 2)[0]
+}
+
+// handleServiceCallback runs an on/off/toggle button and refreshes the listing
+// it belongs to. payload is "<action>:<entity_id>[:<area_id>]".
+func (b *Bot) handleServiceCallback(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) (string, error) {
+	action, rest, _ := strings.Cut(payload, ":")
+	entityID, areaID, _ := strings.Cut(rest, ":")
+
+	// Button actions are named like the commands
+	if run := b.confirmable(ctx, action, entityID); run != nil {
+		b.requestConfirmation(query.Message.Chat.ID, query.From, action, entityID, run)
+		return "Please confirm", nil
+	}
+
+	var notice string
+	var err error
+	switch action {
+	case "on":
+		notice, err = b.handleTurnOn(ctx, entityID)
+	case "off":
+		notice, err = b.handleTurnOff(ctx, entityID)
+	case "toggle":
+		notice, err = b.handleToggle(ctx, entityID)
+	default:
+		return "", fmt.Errorf("unknown action %q", action)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text, keyboard, err := b.serviceView(ctx, entityID, areaID)
+	if err != nil {
+		return "", err
+	}
+	b.editMessage(query.Message.Chat.ID, query.Message.MessageID, text, keyboard)
+
+	// Answer text is plain, drop Markdown
+	return strings.ReplaceAll(notice, "`", ""), nil
+}
+
+// serviceView renders the listing a service button belongs to: the area
+// if given, otherwise the domain of the entity
+func (b *Bot) serviceView(ctx context.Context, entityID, areaID string) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	// Only operators press service buttons, keep them
+	if areaID == "" {
+		return b.handleEntities(ctx, getDomain(entityID), true)
+	}
+
+	reg, err := b.registry(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	area, ok := reg.Area(areaID)
+	if !ok {
+		return "", nil, fmt.Errorf("area %s no longer exists", areaID)
+	}
+	return b.renderArea(ctx, reg, area, true)
+}
+
+// handleStateCallback re-reads the state shown in a /state reply
+func (b *Bot) handleStateCallback(ctx context.Context, query *tgbotapi.CallbackQuery, entityID string) (string, error) {
+	text, err := b.handleState(ctx, entityID)
+	if err != nil {
+		return "", err
+	}
+
+	b.editMessage(query.Message.Chat.ID, query.Message.MessageID, text, stateKeyboard(entityID))
+	return "", nil
+}
+
+// handlePauseCallback pauses notifications from the button under a notification
+func (b *Bot) handlePauseCallback(ctx context.Context, query *tgbotapi.CallbackQuery, duration string) (string, error) {
+	if b.notifSvc == nil {
+		return "", fmt.Errorf("power monitoring is not configured")
+	}
+
+	d, err := parsePauseDuration(duration)
+	if err != nil {
+		return "", err
+	}
+
+	status, err := b.notifSvc.Pause(ctx, d, userName(query.From))
+	if err != nil {
+		return "", err
+	}
+
+	return formatPauseStatus(status, b.notifSvc.Location()), nil
+}
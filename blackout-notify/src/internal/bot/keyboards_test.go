@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+func TestEntitiesKeyboard(t *testing.T) {
+	long := "light." + strings.Repeat("x", 40)
+	allowAll := func(string) bool { return true }
+
+	tests := []struct {
+		name     string
+		entities []homeassistant.Entity
+		areaID   string
+		want     [][]string
+	}{
+		{
+			name:     "domain listing",
+			entities: []homeassistant.Entity{entity("light.kitchen", ""), entity("sensor.temperature", "")},
+			want:     [][]string{{"svc:toggle:light.kitchen", "svc:on:light.kitchen", "svc:off:light.kitchen"}},
+		},
+		{
+			name:     "area listing",
+			entities: []homeassistant.Entity{entity("light.kitchen", "")},
+			areaID:   "kitchen",
+			want:     [][]string{{"svc:toggle:light.kitchen:kitchen", "svc:on:light.kitchen:kitchen", "svc:off:light.kitchen:kitchen"}},
+		},
+		{
+			// The entity fits, the area doesn't: refresh the domain listing instead
+			name:     "area too long",
+			entities: []homeassistant.Entity{entity(long, "")},
+			areaID:   "living_room_downstairs",
+			want:     [][]string{{"svc:toggle:" + long, "svc:on:" + long, "svc:off:" + long}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyboard := entitiesKeyboard(tt.entities, tt.areaID, allowAll)
+			if keyboard == nil {
+				t.Fatal("entitiesKeyboard() = nil")
+			}
+
+			var got [][]string
+			for _, row := range keyboard.InlineKeyboard {
+				var data []string
+				for _, button := range row {
+					data = append(data, *button.CallbackData)
+				}
+				got = append(got, data)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entitiesKeyboard() data = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+)
+
+// maxPauseDuration limits how long notifications can be paused at once
+const maxPauseDuration = 7 * 24 * time.Hour
+
+// pauseDurationPart matches one "<number><unit>" part of a pause duration
+var pauseDurationPart = regexp.MustCompile(`(\d+)\s*(d|д|h|год|г|m|хв|х)`)
+
+// parsePauseDuration parses durations like "2h", "30m", "1h30m", "1d" or
+// "2 год"; a bare number means hours and an empty string pauses indefinitely
+func parsePauseDuration(arg string) (time.Duration, error) {
+	arg = strings.ToLower(strings.TrimSpace(arg))
+	if arg == "" {
+		return 0, nil
+	}
+
+	if hours, err := strconv.Atoi(arg); err == nil {
+		arg = fmt.Sprintf("%dh", hours)
+	}
+
+	matches := pauseDurationPart.FindAllStringSubmatch(arg, -1)
+	if len(matches) == 0 || strings.TrimSpace(pauseDurationPart.ReplaceAllString(arg, "")) != "" {
+		return 0, fmt.Errorf("invalid duration %q, use e.g. /pause 2h or /pause 30m", arg)
+	}
+
+	var d time.Duration
+	for _, m := range matches {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "d", "д":
+			d += time.Duration(n) * 24 * time.Hour
+		case "h", "год", "г":
+			d += time.Duration(n) * time.Hour
+		default:
+			d += time.Duration(n) * time.Minute
+		}
+	}
+
+	if d <= 0 || d > maxPauseDuration {
+		return 0, fmt.Errorf("pause duration must be between 1 minute and %v", maxPauseDuration)
+	}
+	return d, nil
+}
+
+// userName returns how a Telegram user is shown in notes
+func userName(user *tgbotapi.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// handlePause pauses power notifications, optionally for a limited time
+func (b *Bot) handlePause(ctx context.Context, args string, from *tgbotapi.User) (string, error) {
+	if b.notifSvc == nil {
+		return "Power monitoring is not configured. Set watched_entity_id or infer_power_from_connection.", nil
+	}
+
+	d, err := parsePauseDuration(args)
+	if err != nil {
+		return "", err
+	}
+
+	status, err := b.notifSvc.Pause(ctx, d, userName(from))
+	if err != nil {
+		return "", err
+	}
+
+	return formatPauseStatus(status, b.notifSvc.Location()), nil
+}
+
+// handleResume resumes power notifications
+func (b *Bot) handleResume(ctx context.Context, from *tgbotapi.User) (string, error) {
+	if b.notifSvc == nil {
+		return "Power monitoring is not configured. Set watched_entity_id or infer_power_from_connection.", nil
+	}
+
+	if !b.notifSvc.PauseStatus(ctx).Paused {
+		return "Notifications are not paused.", nil
+	}
+
+	if err := b.notifSvc.Resume(ctx, userName(from)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s Notifications resumed", notifications.IconResume), nil
+}
+
+// formatPauseStatus describes a pause in a command reply
+func formatPauseStatus(status notifications.PauseStatus, loc *time.Location) string {
+	if !status.Paused {
+		return "Notifications are not paused."
+	}
+	if status.Until == nil {
+		return fmt.Sprintf("%s Notifications paused until /resume", notifications.IconPause)
+	}
+	return fmt.Sprintf("%s Notifications paused until %s", notifications.IconPause, status.Until.In(loc).Format("02.01 15:04"))
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePauseDuration(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{arg: "", want: 0},
+		{arg: "  ", want: 0},
+		{arg: "2", want: 2 * time.Hour},
+		{arg: "2h", want: 2 * time.Hour},
+		{arg: "30m", want: 30 * time.Minute},
+		{arg: "1h30m", want: 90 * time.Minute},
+		{arg: "1D", want: 24 * time.Hour},
+		{arg: "2 год", want: 2 * time.Hour},
+		{arg: "45хв", want: 45 * time.Minute},
+		{arg: "7d", want: maxPauseDuration},
+		{arg: "0", wantErr: true},
+		{arg: "0h", wantErr: true},
+		{arg: "0h0m", wantErr: true},
+		{arg: "-1", wantErr: true},
+		{arg: "8d", wantErr: true},
+		{arg: "200", wantErr: true},
+		{arg: "abc", wantErr: true},
+		{arg: "2x", wantErr: true},
+		{arg: "1h later", wantErr: true},
+		{arg: "h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePauseDuration(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePauseDuration(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePauseDuration(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
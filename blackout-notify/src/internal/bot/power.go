@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+	"github.com/yourusername/haaddon/telegram-bot/internal/watcher"
+)
+
+// commandAliases maps non-Latin commands, which Telegram doesn't recognize
+// as bot commands, to their Latin equivalents
+var commandAliases = map[string]string{
+	"/світло": "power",
+}
+
+// SetPowerMonitor gives the bot access to the power watcher for /power.
+// Must be called before Start.
+func (b *Bot) SetPowerMonitor(w *watcher.Watcher, notifSvc *notifications.Service) {
+	b.watcher = w
+	b.notifSvc = notifSvc
+}
+
+// parseAlias resolves a message like "/світло" to a command and its arguments
+func parseAlias(text string) (command, args string, ok bool) {
+	fields := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	// Strip the "@botname" suffix used in group chats
+	name := strings.ToLower(strings.SplitN(fields[0], "@", 2)[0])
+
+	command, ok = commandAliases[name]
+	if !ok {
+		return "", "", false
+	}
+	if len(fields) > 1 {
+		args = strings.TrimSpace(fields[1])
+	}
+	return command, args, true
+}
+
+// handlePower reports the current power state, the next scheduled change and
+// today's total outage time
+func (b *Bot) handlePower(ctx context.Context) string {
+	if b.watcher == nil || b.notifSvc == nil {
+		return "Power monitoring is not configured. Set watched_entity_id or infer_power_from_connection."
+	}
+
+	status := b.watcher.Status()
+
+	now := time.Now().In(b.notifSvc.Location())
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	return b.notifSvc.FormatPowerStatus(ctx, notifications.PowerStatus{
+		State:    string(status.State),
+		Since:    status.Since,
+		Inferred: status.Inferred,
+		TodayOff: b.watcher.TotalOff(midnight, now),
+	})
+}
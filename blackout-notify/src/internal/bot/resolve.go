@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+const (
+	// maxEntityChoices limits the buttons offered for an ambiguous entity name
+	maxEntityChoices = 8
+
+	// callbackPick prefixes callback data of entity choice buttons: "pick:<command>:<entity_id>"
+	callbackPick = "pick"
+)
+
+// entityCommands are the commands whose argument is a single entity, resolved by resolveEntity
+var entityCommands = map[string]bool{
+	"state":    true,
+	"turn_on":  true,
+	"on":       true,
+	"turn_off": true,
+	"off":      true,
+	"toggle":   true,
+}
+
+// Match quality of an entity against a search, best last
+const (
+	matchNone = iota
+	matchWords
+	matchPrefix
+	matchExact
+)
+
+// resolveEntity turns what the user typed into an entity ID: an alias from
+// entity_aliases, an existing entity ID, or the single best match of entity
+// IDs and friendly names. Ambiguous searches return the candidates instead;
+// "area:<name>" is kept for the command to resolve.
+// Only entities passing allowed are considered, if set.
+func (b *Bot) resolveEntity(ctx context.Context, query string, allowed func(entityID string) bool) (string, []homeassistant.Entity, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || strings.HasPrefix(query, areaPrefix) {
+		return query, nil, nil
+	}
+
+	if entityID, ok := b.config.EntityAliases[strings.ToLower(strings.Join(strings.Fields(query), " "))]; ok {
+		return entityID, nil, nil
+	}
+
+	entities, err := b.states.GetStates(ctx)
+	if err != nil {
+		// Let the command itself report that Home Assistant is unreachable
+		logger.Debug("Failed to read states to resolve %q: %v", query, err)
+		return query, nil, nil
+	}
+
+	var best []homeassistant.Entity
+	bestMatch := matchNone
+	for _, e := range entities {
+		if e.EntityID == query {
+			return query, nil, nil
+		}
+		if allowed != nil && !allowed(e.EntityID) {
+			continue
+		}
+
+		switch m := matchEntity(query, e); {
+		case m > bestMatch:
+			best, bestMatch = []homeassistant.Entity{e}, m
+		case m == bestMatch && m != matchNone:
+			best = append(best, e)
+		}
+	}
+
+	switch {
+	case len(best) == 1:
+		return best[0].EntityID, nil, nil
+	case len(best) > 1:
+		sort.Slice(best, func(i, j int) bool { return best[i].EntityID < best[j].EntityID })
+		return "", best[:min(len(best), maxEntityChoices)], nil
+	case strings.Contains(query, "."):
+		// Looks like an ID; the command reports whether it exists
+		return query, nil, nil
+	default:
+		return "", nil, fmt.Errorf("no entity matches %q, see /entities", query)
+	}
+}
+
+// matchEntity rates how well a search matches an entity ID or friendly name,
+// ignoring case and treating "_", "-" and "." like spaces
+func matchEntity(query string, e homeassistant.Entity) int {
+	q := normalizeName(query)
+	_, objectID, _ := strings.Cut(e.EntityID, ".")
+	id := normalizeName(objectID)
+	name, _ := e.Attributes["friendly_name"].(string)
+	name = normalizeName(name)
+
+	switch {
+	case q == "":
+		return matchNone
+	case q == id || q == name:
+		return matchExact
+	case strings.HasPrefix(id, q) || (name != "" && strings.HasPrefix(name, q)):
+		return matchPrefix
+	}
+
+	if containsWords(name+" "+normalizeName(e.EntityID), q) {
+		return matchWords
+	}
+	return matchNone
+}
+
+// containsWords checks if every word of a normalized search occurs in s
+func containsWords(s, q string) bool {
+	for _, word := range strings.Fields(q) {
+		if !strings.Contains(s, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeName lowercases a name and turns separators into single spaces
+func normalizeName(s string) string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer("_", " ", "-", " ", ".", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// entityChoiceKeyboard offers the candidates of an ambiguous search, one button each
+func entityChoiceKeyboard(command string, candidates []homeassistant.Entity) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, e := range candidates {
+		label := entityLabel(e)
+		if label != e.EntityID {
+			label += " (" + e.EntityID + ")"
+		}
+		if button, ok := callbackButton(label, callbackPick, command, e.EntityID); ok {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// handlePickCallback runs a command on the entity chosen from an ambiguous
+// search; payload is "<command>:<entity_id>". The buttons can be pressed by
+// anyone in the chat, so the command's role is checked again.
+func (b *Bot) handlePickCallback(ctx context.Context, query *tgbotapi.CallbackQuery, payload string) (string, error) {
+	command, entityID, _ := strings.Cut(payload, ":")
+	if !entityCommands[command] {
+		return "", fmt.Errorf("unknown command %q", command)
+	}
+
+	role := b.config.MemberRole(query.Message.Chat.ID, userID(query.From))
+	if required := requiredRole(commandRoles, command); !config.RoleAtLeast(role, required) {
+		return "", fmt.Errorf("/%s requires the %s role", command, required)
+	}
+
+	if run := b.confirmable(ctx, command, entityID); run != nil {
+		b.requestConfirmation(query.Message.Chat.ID, query.From, command, entityID, run)
+		return "Please confirm", nil
+	}
+
+	var text string
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	var err error
+	switch command {
+	case "state":
+		text, err = b.handleState(ctx, entityID)
+		keyboard = stateKeyboard(entityID)
+	case "turn_on", "on":
+		text, err = b.handleTurnOn(ctx, entityID)
+	case "turn_off", "off":
+		text, err = b.handleTurnOff(ctx, entityID)
+	case "toggle":
+		text, err = b.handleToggle(ctx, entityID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	b.editMessage(query.Message.Chat.ID, query.Message.MessageID, text, keyboard)
+	return "", nil
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+)
+
+func entity(entityID, friendlyName string) homeassistant.Entity {
+	e := homeassistant.Entity{EntityID: entityID, State: "off"}
+	if friendlyName != "" {
+		e.Attributes = map[string]interface{}{"friendly_name": friendlyName}
+	}
+	return e
+}
+
+// newResolveBot creates a bot reading the given states from a fake Home Assistant
+func newResolveBot(t *testing.T, cfg *config.Config, entities []homeassistant.Entity) *Bot {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/states" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(entities); err != nil {
+			t.Errorf("Failed to encode states: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := homeassistant.NewClient(server.URL, "test_token")
+	return &Bot{config: cfg, states: homeassistant.NewStateCache(client, nil, time.Minute)}
+}
+
+func TestMatchEntity(t *testing.T) {
+	e := entity("switch.bedroom_fan_plug_l1", "Bedroom Fan")
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"bedroom_fan_plug_l1", matchExact},
+		{"bedroom fan", matchExact},
+		{"BEDROOM-FAN", matchExact},
+		{"bedroom", matchPrefix},
+		{"bedroom fan p", matchPrefix},
+		{"fan", matchWords},
+		{"fan bedroom", matchWords},
+		{"switch", matchWords},
+		{"kitchen", matchNone},
+		{"  ", matchNone},
+	}
+
+	for _, tt := range tests {
+		if got := matchEntity(tt.query, e); got != tt.want {
+			t.Errorf("matchEntity(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestResolveEntity(t *testing.T) {
+	entities := []homeassistant.Entity{
+		entity("switch.bedroom_fan_plug_l1", "Bedroom Fan"),
+		entity("switch.fan_kitchen", ""),
+		entity("switch.ceiling_fan", "Ceiling"),
+		entity("fan.kitchen_hood", "Hood"),
+		entity("light.kitchen", "Kitchen Light"),
+		entity("light.kitchen_spots", "Kitchen Spots"),
+		entity("lock.front_door", "Front Door"),
+		entity("light.front_porch", "Front Porch"),
+	}
+	for i := 1; i <= maxEntityChoices+2; i++ {
+		entities = append(entities, entity(fmt.Sprintf("light.lamp_%02d", i), ""))
+	}
+
+	cfg := &config.Config{EntityAliases: map[string]string{"fan": "switch.bedroom_fan_plug_l1", "door": "lock.front_door"}}
+	b := newResolveBot(t, cfg, entities)
+	notLocks := func(entityID string) bool { return getDomain(entityID) != "lock" }
+
+	var lamps []string
+	for i := 1; i <= maxEntityChoices; i++ {
+		lamps = append(lamps, fmt.Sprintf("light.lamp_%02d", i))
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		allowed    func(entityID string) bool
+		want       string
+		candidates []string
+		wantErr    bool
+	}{
+		{name: "alias", query: "fan", want: "switch.bedroom_fan_plug_l1"},
+		{name: "alias ignores case and spacing", query: "  Fan ", want: "switch.bedroom_fan_plug_l1"},
+		// Aliases are not filtered, the command itself checks the entity
+		{name: "alias of filtered entity", query: "door", allowed: notLocks, want: "lock.front_door"},
+		{name: "exact ID", query: "light.kitchen", want: "light.kitchen"},
+		{name: "exact friendly name", query: "kitchen light", want: "light.kitchen"},
+		{name: "exact object ID beats prefix", query: "kitchen", want: "light.kitchen"},
+		// fan.kitchen_hood only contains both words
+		{name: "prefix beats word match", query: "fan k", want: "switch.fan_kitchen"},
+		{name: "word match", query: "hood kitchen", want: "fan.kitchen_hood"},
+		{name: "prefix", query: "bedroom", want: "switch.bedroom_fan_plug_l1"},
+		{name: "ambiguous prefix", query: "front", candidates: []string{"light.front_porch", "lock.front_door"}},
+		{name: "ambiguity capped and sorted", query: "lamp", candidates: lamps},
+		{name: "filtered by allowed", query: "front", allowed: notLocks, want: "light.front_porch"},
+		{name: "area target kept", query: "area:bedroom", want: "area:bedroom"},
+		{name: "unknown ID left to the command", query: "light.garage", want: "light.garage"},
+		{name: "no match", query: "garage", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, candidates, err := b.resolveEntity(context.Background(), tt.query, tt.allowed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveEntity(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+
+			var ids []string
+			for _, e := range candidates {
+				ids = append(ids, e.EntityID)
+			}
+			if !reflect.DeepEqual(ids, tt.candidates) {
+				t.Errorf("resolveEntity(%q) candidates = %v, want %v", tt.query, ids, tt.candidates)
+			}
+			if got != tt.want {
+				t.Errorf("resolveEntity(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
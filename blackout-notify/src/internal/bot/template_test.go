@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestTemplateCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		user int64
+		want string
+	}{
+		{name: "operator denied", user: operatorID, want: "⛔ /template requires the admin role."},
+		{name: "admin gets escaped output", user: adminID, want: `ups\_battery: \*85\*%`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, requests, _ := newCallbackBot(t)
+
+			text := "/template {{ states('sensor.ups_battery') }}"
+			b.handleMessage(context.Background(), &tgbotapi.Message{
+				MessageID: 1,
+				From:      &tgbotapi.User{ID: tt.user},
+				Chat:      &tgbotapi.Chat{ID: tt.user},
+				Text:      text,
+				Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/template")}},
+			})
+
+			reply := nextRequest(t, requests, "sendMessage")
+			if got := reply.params.Get("text"); got != tt.want {
+				t.Errorf("reply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,26 +6,66 @@ import (
 	"strings"
 )
 
+// Run modes
+const (
+	ModeAddon    = "addon"    // Watch Home Assistant (default)
+	ModeReceiver = "receiver" // Standalone heartbeat receiver, no Home Assistant
+)
+
 // Config holds all application settings
 type Config struct {
 	// Telegram settings
 	TelegramToken  string
 	AllowedChatIDs []int64
+	EntityAliases  map[string]string // Short names usable instead of entity IDs in bot commands, keyed in lowercase
+
+	// Bot access control
+	BotRoles        map[int64]string // Role per user or chat ID; listed IDs may use the bot even if not in AllowedChatIDs
+	DefaultRole     string           // Role of AllowedChatIDs without an entry in BotRoles
+	GroupMemberRole string           // Highest role of group members without a role of their own (groups without a BotRoles entry)
+	EntityAllow     []string         // Entity patterns the bot may control (empty allows all)
+	EntityDeny      []string         // Entity patterns the bot may never control
+	ConfirmEntities []string         // Entity patterns whose control from Telegram must be confirmed
+	ConfirmTimeout  int              // Seconds a pending confirmation stays valid
 
 	// Home Assistant settings
-	HAApiURL string
-	HAToken  string
+	HAApiURL        string
+	HAToken         string
+	HARetryAttempts int // Attempts for read requests while HA is unavailable (1 disables retries)
 
 	// General settings
+	Mode            string // Run mode: "addon" or "receiver"
 	LogLevel        string
 	PollingInterval int
+	DataDir         string // Directory for persistent data (outage journal, etc.)
 
 	// Power monitoring settings
 	NotificationChatIDs []int64 // Chat IDs for power notifications (can be channels)
 	WatchedEntityID     string  // Entity ID of power sensor (e.g., binary_sensor.power)
 	NextOnSensorID      string  // Entity ID of sensor with next power on time
 	NextOffSensorID     string  // Entity ID of sensor with next power off time
+	CalendarEntityID    string  // Entity ID of calendar with scheduled outages (alternative to next on/off sensors)
 	PauseEntityID       string  // Entity ID of input_boolean to pause notifications
+	PublishSensors      bool    // Publish add-on computed sensors (outage duration, etc.) to HA
+	FireEvents          bool    // Fire HA events on power transitions and schedule changes
+	HistoryBackfillDays int     // Days of HA history used to fill an empty outage journal (0 disables)
+	PowerOnTemplate     string  // HA Jinja template appended to power on notifications
+	PowerOffTemplate    string  // HA Jinja template appended to power off notifications
+
+	// Connection alerts
+	ConnectionAlertMinutes int // Alert admins when HA is unreachable this long (0 disables)
+
+	// Remote mode: power state inferred from connectivity to HA
+	InferPowerFromConnection bool // Treat prolonged loss of connection to HA as a power outage
+	ConnectionOffMinutes     int  // Minutes without connection before power is considered off
+	ConnectionOnMinutes      int  // Minutes of restored connection before power is considered on
+
+	// Heartbeat (dead man's switch) settings
+	HeartbeatURL      string // Receiver endpoint the in-house instance pings (sender side)
+	HeartbeatToken    string // Shared secret between sender and receiver
+	HeartbeatInterval int    // Seconds between pings (sender side)
+	HeartbeatListen   string // Address the receiver listens on (receiver side)
+	HeartbeatTimeout  int    // Seconds without pings before an outage is announced (receiver side)
 
 	// Timezone for formatting
 	Timezone string
@@ -37,15 +77,45 @@ func Load() (*Config, error) {
 		TelegramToken:   os.Getenv("TELEGRAM_TOKEN"),
 		HAApiURL:        getEnvOrDefault("HA_API_URL", "http://supervisor/core/api"),
 		HAToken:         os.Getenv("HA_TOKEN"),
+		HARetryAttempts: getEnvAsInt("HA_RETRY_ATTEMPTS", 3),
+		Mode:            getEnvOrDefault("MODE", ModeAddon),
 		LogLevel:        getEnvOrDefault("LOG_LEVEL", "info"),
 		PollingInterval: getEnvAsInt("POLLING_INTERVAL", 30),
+		DataDir:         getEnvOrDefault("DATA_DIR", "/data"),
+
+		BotRoles:        parseRoles(os.Getenv("BOT_ROLES")),
+		DefaultRole:     strings.ToLower(getEnvOrDefault("BOT_DEFAULT_ROLE", RoleAdmin)),
+		GroupMemberRole: strings.ToLower(getEnvOrDefault("GROUP_MEMBER_ROLE", RoleViewer)),
+		EntityAllow:     parsePatterns(os.Getenv("ENTITY_ALLOW")),
+		EntityDeny:      parsePatterns(os.Getenv("ENTITY_DENY")),
+		ConfirmEntities: parsePatterns(getEnvOrDefault("CONFIRM_ENTITIES", "lock.*,alarm_control_panel.*")),
+		ConfirmTimeout:  getEnvAsInt("CONFIRM_TIMEOUT", 60),
 
 		// Power monitoring settings
-		WatchedEntityID: os.Getenv("WATCHED_ENTITY_ID"),
-		NextOnSensorID:  os.Getenv("NEXT_ON_SENSOR_ID"),
-		NextOffSensorID: os.Getenv("NEXT_OFF_SENSOR_ID"),
-		PauseEntityID:   getEnvOrDefault("PAUSE_ENTITY_ID", "input_boolean.pause_power_notifications"),
-		Timezone:        getEnvOrDefault("TIMEZONE", "Europe/Kyiv"),
+		WatchedEntityID:     os.Getenv("WATCHED_ENTITY_ID"),
+		NextOnSensorID:      os.Getenv("NEXT_ON_SENSOR_ID"),
+		NextOffSensorID:     os.Getenv("NEXT_OFF_SENSOR_ID"),
+		CalendarEntityID:    os.Getenv("CALENDAR_ENTITY_ID"),
+		PauseEntityID:       getEnvOrDefault("PAUSE_ENTITY_ID", "input_boolean.pause_power_notifications"),
+		PublishSensors:      getEnvAsBool("PUBLISH_SENSORS", true),
+		FireEvents:          getEnvAsBool("FIRE_EVENTS", true),
+		HistoryBackfillDays: getEnvAsInt("HISTORY_BACKFILL_DAYS", 7),
+		PowerOnTemplate:     os.Getenv("POWER_ON_TEMPLATE"),
+		PowerOffTemplate:    os.Getenv("POWER_OFF_TEMPLATE"),
+
+		ConnectionAlertMinutes: getEnvAsInt("CONNECTION_ALERT_MINUTES", 10),
+
+		InferPowerFromConnection: getEnvAsBool("INFER_POWER_FROM_CONNECTION", false),
+		ConnectionOffMinutes:     getEnvAsInt("CONNECTION_OFF_MINUTES", 3),
+		ConnectionOnMinutes:      getEnvAsInt("CONNECTION_ON_MINUTES", 1),
+
+		HeartbeatURL:      os.Getenv("HEARTBEAT_URL"),
+		HeartbeatToken:    os.Getenv("HEARTBEAT_TOKEN"),
+		HeartbeatInterval: getEnvAsInt("HEARTBEAT_INTERVAL", 30),
+		HeartbeatListen:   getEnvOrDefault("HEARTBEAT_LISTEN", ":8099"),
+		HeartbeatTimeout:  getEnvAsInt("HEARTBEAT_TIMEOUT", 180),
+
+		Timezone: getEnvOrDefault("TIMEZONE", "Europe/Kyiv"),
 	}
 
 	// Parse allowed chat IDs
@@ -54,6 +124,8 @@ func Load() (*Config, error) {
 		cfg.AllowedChatIDs = parseChatIDs(chatIDsStr)
 	}
 
+	cfg.EntityAliases = parseAliases(os.Getenv("ENTITY_ALIASES"))
+
 	// Parse notification chat IDs (for power notifications)
 	notifChatIDsStr := os.Getenv("NOTIFICATION_CHAT_IDS")
 	if notifChatIDsStr != "" {
@@ -63,29 +135,35 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// IsChatAllowed checks if chat ID is allowed
+// IsChatAllowed checks if chat ID is allowed, i.e. has any role
 func (c *Config) IsChatAllowed(chatID int64) bool {
-	// If list is empty - deny all (safe by default)
-	if len(c.AllowedChatIDs) == 0 {
-		return false
-	}
-
-	for _, allowed := range c.AllowedChatIDs {
-		if allowed == chatID {
-			return true
-		}
-	}
-	return false
+	// If both lists are empty - deny all (safe by default)
+	return c.ChatRole(chatID) != ""
 }
 
 // IsBotCommandsEnabled checks if bot commands are enabled
 func (c *Config) IsBotCommandsEnabled() bool {
-	return len(c.AllowedChatIDs) > 0
+	return len(c.AllowedChatIDs) > 0 || len(c.BotRoles) > 0
 }
 
 // IsPowerMonitoringEnabled checks if power monitoring is configured
 func (c *Config) IsPowerMonitoringEnabled() bool {
-	return c.WatchedEntityID != "" && len(c.NotificationChatIDs) > 0
+	return (c.WatchedEntityID != "" || c.InferPowerFromConnection) && len(c.NotificationChatIDs) > 0
+}
+
+// IsReceiverMode checks if the binary runs as a standalone heartbeat receiver
+func (c *Config) IsReceiverMode() bool {
+	return c.Mode == ModeReceiver
+}
+
+// IsHeartbeatEnabled checks if heartbeats are sent to a remote receiver
+func (c *Config) IsHeartbeatEnabled() bool {
+	return c.HeartbeatURL != ""
+}
+
+// IsCalendarScheduleEnabled checks if the outage schedule is read from a calendar
+func (c *Config) IsCalendarScheduleEnabled() bool {
+	return c.CalendarEntityID != ""
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -104,6 +182,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func parseChatIDs(str string) []int64 {
 	// Handle empty or null values
 	str = strings.TrimSpace(str)
@@ -131,3 +218,24 @@ func parseChatIDs(str string) []int64 {
 	}
 	return ids
 }
+
+// parseAliases parses "fan=switch.bedroom_fan_plug_l1, kitchen light=light.kitchen".
+// Aliases may contain spaces, so entries are separated by commas or new lines only.
+func parseAliases(str string) map[string]string {
+	str = strings.TrimSpace(str)
+	if str == "" || str == "null" {
+		return nil
+	}
+
+	aliases := make(map[string]string)
+	for _, entry := range strings.FieldsFunc(str, func(r rune) bool { return r == ',' || r == '\n' }) {
+		alias, entityID, ok := strings.Cut(entry, "=")
+		alias = strings.ToLower(strings.Join(strings.Fields(alias), " "))
+		entityID = strings.TrimSpace(entityID)
+		if !ok || alias == "" || !strings.Contains(entityID, ".") {
+			continue
+		}
+		aliases[alias] = entityID
+	}
+	return aliases
+}
@@ -116,6 +116,43 @@ func TestIsBotCommandsEnabled(t *testing.T) {
 	}
 }
 
+func TestIsPowerMonitoringEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want bool
+	}{
+		{
+			name: "watched entity",
+			cfg:  Config{WatchedEntityID: "binary_sensor.power", NotificationChatIDs: []int64{-100}},
+			want: true,
+		},
+		{
+			name: "inferred from connection without entity",
+			cfg:  Config{InferPowerFromConnection: true, NotificationChatIDs: []int64{-100}},
+			want: true,
+		},
+		{
+			name: "no notification chats",
+			cfg:  Config{WatchedEntityID: "binary_sensor.power", InferPowerFromConnection: true},
+			want: false,
+		},
+		{
+			name: "no power source",
+			cfg:  Config{NotificationChatIDs: []int64{-100}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.IsPowerMonitoringEnabled(); got != tt.want {
+				t.Errorf("IsPowerMonitoringEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseChatIDs(t *testing.T) {
 	tests := []struct {
 		input string
@@ -143,3 +180,160 @@ func TestParseChatIDs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvAsBool(t *testing.T) {
+	tests := []struct {
+		value        string
+		defaultValue bool
+		want         bool
+	}{
+		{"true", false, true},
+		{"false", true, false},
+		{"1", false, true},
+		{"", true, true},
+		{"invalid", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			os.Setenv("TEST_BOOL", tt.value)
+			defer os.Unsetenv("TEST_BOOL")
+
+			if got := getEnvAsBool("TEST_BOOL", tt.defaultValue); got != tt.want {
+				t.Errorf("getEnvAsBool(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatRole(t *testing.T) {
+	cfg := &Config{
+		AllowedChatIDs: []int64{100, 200},
+		BotRoles:       parseRoles("200:viewer, -300:operator 400:superuser 500:ADMIN"),
+		DefaultRole:    RoleOperator,
+	}
+
+	tests := []struct {
+		chatID int64
+		want   string
+	}{
+		{100, RoleOperator}, // allowed chat gets the default role
+		{200, RoleViewer},   // explicit role wins
+		{-300, RoleOperator},
+		{400, ""}, // unknown role skipped
+		{500, RoleAdmin},
+		{999, ""},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.ChatRole(tt.chatID); got != tt.want {
+			t.Errorf("ChatRole(%d) = %q, want %q", tt.chatID, got, tt.want)
+		}
+	}
+
+	if !cfg.IsChatAllowed(-300) || cfg.IsChatAllowed(400) {
+		t.Error("IsChatAllowed() should follow roles")
+	}
+	if !cfg.HasRole(-300, RoleViewer) || cfg.HasRole(-300, RoleAdmin) {
+		t.Error("HasRole() should compare role ranks")
+	}
+	if ids := cfg.AdminChatIDs(); len(ids) != 1 || ids[0] != 500 {
+		t.Errorf("AdminChatIDs() = %v, want [500]", ids)
+	}
+}
+
+func TestMemberRole(t *testing.T) {
+	cfg := &Config{
+		AllowedChatIDs: []int64{-100, 10},
+		BotRoles:       parseRoles("20:operator, -200:operator, 30:viewer"),
+		DefaultRole:    RoleAdmin,
+	}
+
+	tests := []struct {
+		name           string
+		chatID, userID int64
+		want           string
+	}{
+		{"private chat", 10, 10, RoleAdmin},
+		{"private chat of listed user", 20, 20, RoleOperator},
+		{"unknown private chat", 40, 40, ""},
+		{"group member capped", -100, 40, RoleViewer},
+		{"listed user in group", -100, 20, RoleOperator},
+		{"admin user in group", -100, 10, RoleAdmin},
+		{"explicit group role", -200, 40, RoleOperator},
+		{"user role below group role", -200, 30, RoleOperator},
+		{"anonymous in group", -100, 0, RoleViewer},
+		{"listed user in unknown group", -300, 20, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.MemberRole(tt.chatID, tt.userID); got != tt.want {
+				t.Errorf("MemberRole(%d, %d) = %q, want %q", tt.chatID, tt.userID, got, tt.want)
+			}
+		})
+	}
+
+	cfg.GroupMemberRole = RoleOperator
+	if got := cfg.MemberRole(-100, 40); got != RoleOperator {
+		t.Errorf("MemberRole() with GroupMemberRole = %q, want operator", got)
+	}
+}
+
+func TestIsEntityAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    string
+		deny     string
+		entityID string
+		want     bool
+	}{
+		{"no patterns", "", "", "lock.front_door", true},
+		{"allowed domain", "light.*, switch.*", "", "light.kitchen", true},
+		{"not in allow list", "light.*, switch.*", "", "lock.front_door", false},
+		{"denied domain", "", "lock.*", "lock.front_door", false},
+		{"deny wins over allow", "switch.*", "switch.boiler", "switch.boiler", false},
+		{"other switch allowed", "switch.*", "switch.boiler", "switch.fan", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{EntityAllow: parsePatterns(tt.allow), EntityDeny: parsePatterns(tt.deny)}
+			if got := cfg.IsEntityAllowed(tt.entityID); got != tt.want {
+				t.Errorf("IsEntityAllowed(%s) = %v, want %v", tt.entityID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsConfirmation(t *testing.T) {
+	cfg := &Config{ConfirmEntities: parsePatterns("lock.*, switch.fridge")}
+
+	for entityID, want := range map[string]bool{
+		"lock.front_door": true,
+		"switch.fridge":   true,
+		"switch.fan":      false,
+		"light.kitchen":   false,
+	} {
+		if got := cfg.NeedsConfirmation(entityID); got != want {
+			t.Errorf("NeedsConfirmation(%s) = %v, want %v", entityID, got, want)
+		}
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	aliases := parseAliases("fan=switch.bedroom_fan_plug_l1,  Kitchen  Light = light.kitchen\nbroken, nodot=light")
+
+	want := map[string]string{
+		"fan":           "switch.bedroom_fan_plug_l1",
+		"kitchen light": "light.kitchen",
+	}
+	if len(aliases) != len(want) {
+		t.Fatalf("parseAliases() = %v, want %v", aliases, want)
+	}
+	for alias, entityID := range want {
+		if aliases[alias] != entityID {
+			t.Errorf("aliases[%q] = %q, want %q", alias, aliases[alias], entityID)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bot roles, from least to most privileged
+const (
+	RoleViewer   = "viewer"   // Read-only commands
+	RoleOperator = "operator" // Also controls entities and pauses notifications
+	RoleAdmin    = "admin"    // Also calls arbitrary services and receives connection alerts
+)
+
+// roleRanks orders roles by privilege
+var roleRanks = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// IsValidRole checks if role is a known bot role
+func IsValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAtLeast reports whether role grants everything required grants
+func RoleAtLeast(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// ChatRole returns the role of a chat: its entry in BotRoles, or DefaultRole
+// for chats listed in AllowedChatIDs. Empty if the chat has no access.
+func (c *Config) ChatRole(chatID int64) string {
+	if role, ok := c.BotRoles[chatID]; ok {
+		return role
+	}
+
+	for _, allowed := range c.AllowedChatIDs {
+		if allowed == chatID {
+			if IsValidRole(c.DefaultRole) {
+				return c.DefaultRole
+			}
+			return RoleAdmin
+		}
+	}
+	return ""
+}
+
+// HasRole checks if a chat has at least the required role
+func (c *Config) HasRole(chatID int64, required string) bool {
+	return RoleAtLeast(c.ChatRole(chatID), required)
+}
+
+// MemberRole returns the role of a user writing in a chat; empty if denied.
+// In private chats (chatID == userID) it is the user's role. In groups the
+// chat must have access itself; members get its role, capped at
+// GroupMemberRole unless the group has an explicit entry in BotRoles, and
+// users with a role of their own get the higher of both.
+func (c *Config) MemberRole(chatID, userID int64) string {
+	chatRole := c.ChatRole(chatID)
+	if chatID == userID || chatRole == "" {
+		return chatRole
+	}
+
+	if _, explicit := c.BotRoles[chatID]; !explicit && RoleAtLeast(chatRole, c.groupMemberRole()) {
+		chatRole = c.groupMemberRole()
+	}
+
+	// Channel posts and anonymous admins have no user
+	if userID == 0 {
+		return chatRole
+	}
+	if userRole := c.ChatRole(userID); RoleAtLeast(userRole, chatRole) {
+		return userRole
+	}
+	return chatRole
+}
+
+// groupMemberRole returns GroupMemberRole, viewer if unset or invalid
+func (c *Config) groupMemberRole() string {
+	if IsValidRole(c.GroupMemberRole) {
+		return c.GroupMemberRole
+	}
+	return RoleViewer
+}
+
+// AdminChatIDs returns the chats with the admin role
+func (c *Config) AdminChatIDs() []int64 {
+	var ids []int64
+	for _, id := range c.AllowedChatIDs {
+		if _, explicit := c.BotRoles[id]; !explicit && c.HasRole(id, RoleAdmin) {
+			ids = append(ids, id)
+		}
+	}
+	for id, role := range c.BotRoles {
+		if role == RoleAdmin {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// IsEntityAllowed checks an entity against the EntityAllow and EntityDeny
+// glob patterns (e.g. "light.*"). Deny wins; an empty allow list allows all.
+func (c *Config) IsEntityAllowed(entityID string) bool {
+	if matchesAny(c.EntityDeny, entityID) {
+		return false
+	}
+	return len(c.EntityAllow) == 0 || matchesAny(c.EntityAllow, entityID)
+}
+
+// NeedsConfirmation checks if controlling an entity must be confirmed (ConfirmEntities)
+func (c *Config) NeedsConfirmation(entityID string) bool {
+	return matchesAny(c.ConfirmEntities, entityID)
+}
+
+// HasEntityRestrictions checks if entity allow or deny patterns are configured
+func (c *Config) HasEntityRestrictions() bool {
+	return len(c.EntityAllow) > 0 || len(c.EntityDeny) > 0
+}
+
+// matchesAny checks an entity ID against glob patterns
+func matchesAny(patterns []string, entityID string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, entityID); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRoles parses "123:admin, -100456:viewer"; entries with an unknown role are skipped
+func parseRoles(str string) map[int64]string {
+	str = strings.TrimSpace(str)
+	if str == "" || str == "null" {
+		return nil
+	}
+
+	roles := make(map[int64]string)
+	for _, entry := range strings.FieldsFunc(str, isListSeparator) {
+		idStr, role, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+		role = strings.ToLower(strings.TrimSpace(role))
+		if err != nil || !IsValidRole(role) {
+			continue
+		}
+		roles[id] = role
+	}
+	return roles
+}
+
+// parsePatterns parses a comma or space separated list of entity patterns
+func parsePatterns(str string) []string {
+	str = strings.TrimSpace(str)
+	if str == "" || str == "null" {
+		return nil
+	}
+	return strings.FieldsFunc(str, isListSeparator)
+}
+
+// isListSeparator splits option lists on commas and whitespace
+func isListSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\n' || r == '\t'
+}
@@ -0,0 +1,130 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/outages"
+)
+
+type fakeNotifier struct {
+	mu       sync.Mutex
+	lost     []time.Time
+	restored chan time.Duration
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{restored: make(chan time.Duration, 1)}
+}
+
+func (n *fakeNotifier) NotifyHeartbeatLost(lastSeen time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lost = append(n.lost, lastSeen)
+	return nil
+}
+
+func (n *fakeNotifier) NotifyHeartbeatRestored(outageDuration time.Duration) error {
+	n.restored <- outageDuration
+	return nil
+}
+
+func TestReceiverOutage(t *testing.T) {
+	journal, _ := outages.NewJournal("")
+	notifier := newFakeNotifier()
+	r := NewReceiver("secret", time.Minute, notifier, journal)
+
+	start := time.Now()
+	r.beat(start)
+
+	// Within the timeout nothing happens
+	r.check(start.Add(30 * time.Second))
+	if len(notifier.lost) != 0 {
+		t.Fatal("Outage announced before the timeout")
+	}
+
+	// Announced once, starting at the last heartbeat
+	r.check(start.Add(2 * time.Minute))
+	r.check(start.Add(3 * time.Minute))
+	if len(notifier.lost) != 1 || !notifier.lost[0].Equal(start) {
+		t.Fatalf("Lost notifications = %v, want one at %v", notifier.lost, start)
+	}
+	if current := journal.Current(); current == nil || !current.Start.Equal(start) {
+		t.Errorf("Journal Current() = %v, want outage started at %v", current, start)
+	}
+
+	r.beat(start.Add(time.Hour))
+	select {
+	case d := <-notifier.restored:
+		if d != time.Hour {
+			t.Errorf("Outage duration = %v, want 1h", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Restoration was not announced")
+	}
+
+	if journal.Current() != nil || r.Status().Down {
+		t.Error("Outage should be finished after a heartbeat")
+	}
+}
+
+func TestReceiverResumesOngoingOutage(t *testing.T) {
+	journal, _ := outages.NewJournal("")
+	start := time.Now().Add(-time.Hour)
+	_ = journal.Begin(start)
+
+	r := NewReceiver("", time.Minute, newFakeNotifier(), journal)
+	if status := r.Status(); !status.Down || !status.LastSeen.Equal(start) {
+		t.Errorf("Status() = %+v, want down since %v", status, start)
+	}
+}
+
+func TestSenderToReceiver(t *testing.T) {
+	journal, _ := outages.NewJournal("")
+	r := NewReceiver("secret", time.Minute, newFakeNotifier(), journal)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	before := r.Status().LastSeen
+
+	if err := NewSender(server.URL, "wrong", time.Second).Send(context.Background()); err == nil {
+		t.Error("Send() with wrong token should fail")
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := NewSender(server.URL, "secret", time.Second).Send(context.Background()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !r.Status().LastSeen.After(before) {
+		t.Error("Heartbeat was not recorded")
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("PUT status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSenderIntervalDefault(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		s := NewSender("http://localhost", "", interval)
+		if s.interval != defaultSendInterval {
+			t.Errorf("NewSender(%v).interval = %v, want %v", interval, s.interval, defaultSendInterval)
+		}
+	}
+
+	// Run must not panic on the clamped interval
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	NewSender("http://localhost", "", 0).Run(ctx)
+}
@@ -0,0 +1,187 @@
+package heartbeat
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+	"github.com/yourusername/haaddon/telegram-bot/internal/outages"
+)
+
+// Path is the HTTP path heartbeats are sent to
+const Path = "/heartbeat"
+
+// Notifier announces outages detected by the receiver
+type Notifier interface {
+	NotifyHeartbeatLost(lastSeen time.Time) error
+	NotifyHeartbeatRestored(outageDuration time.Duration) error
+}
+
+// Status is the receiver state returned by GET /heartbeat
+type Status struct {
+	LastSeen time.Time `json:"last_seen"`
+	Down     bool      `json:"down"`
+}
+
+// Receiver is a dead man's switch: it expects periodic heartbeats from the
+// in-house instance and announces an outage when they stop
+type Receiver struct {
+	token    string
+	timeout  time.Duration
+	notifier Notifier
+	journal  *outages.Journal
+
+	mu       sync.Mutex
+	lastSeen time.Time
+	down     bool
+}
+
+// NewReceiver creates a receiver. An ongoing outage in the journal (e.g. the
+// receiver restarted while the house was down) is resumed.
+func NewReceiver(token string, timeout time.Duration, notifier Notifier, journal *outages.Journal) *Receiver {
+	r := &Receiver{
+		token:    token,
+		timeout:  timeout,
+		notifier: notifier,
+		journal:  journal,
+		lastSeen: time.Now(),
+	}
+
+	if current := journal.Current(); current != nil {
+		r.down = true
+		r.lastSeen = current.Start
+	}
+
+	return r
+}
+
+// Run serves heartbeats on addr and checks for missed ones until ctx is cancelled
+func (r *Receiver) Run(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, r)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	logger.Info("Heartbeat receiver listening on %s%s (timeout %v)", addr, Path, r.timeout)
+
+	// Check often enough to announce an outage close to the timeout
+	ticker := time.NewTicker(min(r.timeout/6, 10*time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case now := <-ticker.C:
+			r.check(now)
+		}
+	}
+}
+
+// ServeHTTP records a heartbeat (POST) or reports the receiver status (GET)
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !r.authorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		r.beat(time.Now())
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Status())
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Status returns the current receiver state
+func (r *Receiver) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Status{LastSeen: r.lastSeen, Down: r.down}
+}
+
+// authorized checks the bearer token; an empty token disables the check
+func (r *Receiver) authorized(req *http.Request) bool {
+	if r.token == "" {
+		return true
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) == 1
+}
+
+// beat records a heartbeat received at now, ending an ongoing outage
+func (r *Receiver) beat(now time.Time) {
+	r.mu.Lock()
+	wasDown := r.down
+	outageStart := r.lastSeen
+	r.lastSeen = now
+	r.down = false
+	r.mu.Unlock()
+
+	if !wasDown {
+		return
+	}
+
+	// Includes the time the in-house instance needed to boot
+	duration := now.Sub(outageStart)
+	logger.Info("Heartbeats resumed after %v", duration.Round(time.Second))
+
+	if err := r.journal.End(now); err != nil {
+		logger.Warn("Failed to record outage: %v", err)
+	}
+
+	go func() {
+		if err := r.notifier.NotifyHeartbeatRestored(duration); err != nil {
+			logger.Error("Failed to send power on notification: %v", err)
+		}
+	}()
+}
+
+// check announces an outage if no heartbeat arrived within the timeout
+func (r *Receiver) check(now time.Time) {
+	r.mu.Lock()
+	if r.down || now.Sub(r.lastSeen) < r.timeout {
+		r.mu.Unlock()
+		return
+	}
+	r.down = true
+	lastSeen := r.lastSeen
+	r.mu.Unlock()
+
+	logger.Info("No heartbeat since %s, announcing outage", lastSeen.Format(time.RFC3339))
+
+	// The outage started right after the last heartbeat, not when the timeout passed
+	if err := r.journal.Begin(lastSeen); err != nil {
+		logger.Warn("Failed to record outage: %v", err)
+	}
+
+	if err := r.notifier.NotifyHeartbeatLost(lastSeen); err != nil {
+		logger.Error("Failed to send power off notification: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// defaultSendInterval applies when the configured interval is not positive
+const defaultSendInterval = 30 * time.Second
+
+// Sender pings a remote receiver periodically. It runs in the in-house
+// instance: when the house loses power, the pings stop.
+type Sender struct {
+	url        string
+	token      string
+	interval   time.Duration
+	httpClient *http.Client
+}
+
+// NewSender creates a heartbeat sender; a non-positive interval uses the default
+func NewSender(url, token string, interval time.Duration) *Sender {
+	if interval <= 0 {
+		interval = defaultSendInterval
+	}
+
+	return &Sender{
+		url:      url,
+		token:    token,
+		interval: interval,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Run sends heartbeats until ctx is cancelled
+func (s *Sender) Run(ctx context.Context) {
+	logger.Info("Sending heartbeats to %s every %v", s.url, s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		if err := s.Send(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// Log once per failure streak, the receiver may be down for a while
+			if !failing {
+				logger.Warn("Failed to send heartbeat: %v", err)
+			}
+			failing = true
+		} else if failing {
+			logger.Info("Heartbeat receiver reachable again")
+			failing = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Send sends a single heartbeat
+func (s *Sender) Send(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package homeassistant
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// StateReader reads entity states; implemented by Client (always REST) and StateCache
+type StateReader interface {
+	GetState(ctx context.Context, entityID string) (*Entity, error)
+	GetStates(ctx context.Context) ([]Entity, error)
+	GetEntitiesByDomain(ctx context.Context, domain string) ([]Entity, error)
+}
+
+// cachedEntity is a REST snapshot entry
+type cachedEntity struct {
+	entity    Entity
+	fetchedAt time.Time
+}
+
+// StateCache serves entity states from memory. Entities subscribed over the
+// WebSocket (watched and tracked ones) are always current while connected;
+// the rest come from a REST snapshot refreshed when older than ttl. While the
+// socket is down, reads go to REST so that stale values aren't served.
+//
+// Only subscribed entities are fed by the WebSocket stream, not every entity:
+// subscribe_entities is limited to the add-on's own entities to keep traffic
+// low on large installations, so /state, /entities and /area may show states
+// up to ttl (polling_interval) old. Service calls from the bot invalidate the
+// entities they touch.
+type StateCache struct {
+	client *Client
+	ws     *WSClient
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	entities  map[string]cachedEntity
+	fetchedAt time.Time // When the full snapshot was taken
+}
+
+// NewStateCache creates a cache reading through client and kept current by ws (may be nil)
+func NewStateCache(client *Client, ws *WSClient, ttl time.Duration) *StateCache {
+	c := &StateCache{
+		client:   client,
+		ws:       ws,
+		ttl:      ttl,
+		entities: make(map[string]cachedEntity),
+	}
+
+	// Refill the snapshot after every (re)connection, changes may have been missed
+	if ws != nil {
+		ws.OnConnectionEvent(func(event ConnectionEvent) {
+			if event.Type != ConnEventConnected {
+				return
+			}
+			go func() {
+				if err := c.Refresh(context.Background()); err != nil {
+					logger.Debug("Failed to refresh state cache: %v", err)
+				}
+			}()
+		})
+	}
+
+	return c
+}
+
+// Track keeps the given entities current over the WebSocket
+func (c *StateCache) Track(entityIDs ...string) {
+	if c.ws != nil {
+		c.ws.Track(entityIDs...)
+	}
+}
+
+// Refresh replaces the snapshot with all states from the REST API
+func (c *StateCache) Refresh(ctx context.Context) error {
+	entities, err := c.client.GetStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	snapshot := make(map[string]cachedEntity, len(entities))
+	for _, e := range entities {
+		snapshot[e.EntityID] = cachedEntity{entity: e, fetchedAt: now}
+	}
+
+	c.mu.Lock()
+	c.entities = snapshot
+	c.fetchedAt = now
+	c.mu.Unlock()
+
+	logger.Debug("State cache refreshed with %d entities", len(entities))
+	return nil
+}
+
+// Invalidate marks the given entities as stale, or the whole snapshot if none
+// are given, so the next read fetches them again (e.g. after a service call)
+func (c *StateCache) Invalidate(entityIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(entityIDs) == 0 {
+		c.fetchedAt = time.Time{}
+		c.entities = make(map[string]cachedEntity)
+		return
+	}
+	for _, id := range entityIDs {
+		if cached, ok := c.entities[id]; ok {
+			cached.fetchedAt = time.Time{}
+			c.entities[id] = cached
+		}
+	}
+}
+
+// GetState returns the state of an entity
+func (c *StateCache) GetState(ctx context.Context, entityID string) (*Entity, error) {
+	if c.ws != nil {
+		if entity, ok := c.ws.CachedState(entityID); ok {
+			return entity, nil
+		}
+	}
+
+	c.mu.Lock()
+	cached, ok := c.entities[entityID]
+	c.mu.Unlock()
+	if ok && c.usable(cached.fetchedAt) {
+		entity := cached.entity
+		return entity.clone(), nil
+	}
+
+	entity, err := c.client.GetState(ctx, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entities[entityID] = cachedEntity{entity: *entity, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return entity, nil
+}
+
+// GetStates returns all entity states, sorted by entity ID
+func (c *StateCache) GetStates(ctx context.Context) ([]Entity, error) {
+	c.mu.Lock()
+	fetchedAt := c.fetchedAt
+	for _, cached := range c.entities {
+		// An invalidated entry makes the whole snapshot stale
+		if cached.fetchedAt.IsZero() {
+			fetchedAt = time.Time{}
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if !c.usable(fetchedAt) {
+		if err := c.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	entities := make([]Entity, 0, len(c.entities))
+	for _, cached := range c.entities {
+		entities = append(entities, cached.entity)
+	}
+	c.mu.Unlock()
+
+	// Live states win over the snapshot
+	if c.ws != nil {
+		for i := range entities {
+			if live, ok := c.ws.CachedState(entities[i].EntityID); ok {
+				entities[i] = *live
+			}
+		}
+	}
+
+	sort.Slice(entities, func(a, b int) bool {
+		return entities[a].EntityID < entities[b].EntityID
+	})
+
+	return entities, nil
+}
+
+// usable reports whether snapshot data fetched at the given time may be served
+func (c *StateCache) usable(fetchedAt time.Time) bool {
+	if c.ws != nil && !c.ws.IsConnected() {
+		return false
+	}
+	return time.Since(fetchedAt) < c.ttl
+}
+
+// GetEntitiesByDomain returns entities of a specific domain
+func (c *StateCache) GetEntitiesByDomain(ctx context.Context, domain string) ([]Entity, error) {
+	all, err := c.GetStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Entity
+	prefix := domain + "."
+	for _, entity := range all {
+		if strings.HasPrefix(entity.EntityID, prefix) {
+			filtered = append(filtered, entity)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,199 @@
+package homeassistant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStatesServer serves /states and /states/<id> with the given state for every entity
+func newStatesServer(state string, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/states":
+			w.Write([]byte(`[
+				{"entity_id": "light.kitchen", "state": "` + state + `"},
+				{"entity_id": "input_boolean.pause", "state": "` + state + `"}
+			]`))
+		case "/states/light.kitchen", "/states/input_boolean.pause":
+			w.Write([]byte(`{"entity_id": "` + r.URL.Path[len("/states/"):] + `", "state": "` + state + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestStateCacheSnapshot(t *testing.T) {
+	var requests atomic.Int32
+	server := newStatesServer("off", &requests)
+	defer server.Close()
+
+	cache := NewStateCache(NewClient(server.URL, "test_token"), nil, time.Minute)
+	ctx := context.Background()
+
+	entities, err := cache.GetStates(ctx)
+	if err != nil {
+		t.Fatalf("GetStates() error = %v", err)
+	}
+	if len(entities) != 2 || entities[0].EntityID != "input_boolean.pause" {
+		t.Errorf("GetStates() = %v, want 2 entities sorted by ID", entities)
+	}
+
+	// Served from the snapshot
+	if _, err := cache.GetState(ctx, "light.kitchen"); err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if lights, _ := cache.GetEntitiesByDomain(ctx, "light"); len(lights) != 1 {
+		t.Errorf("GetEntitiesByDomain(light) = %v, want 1 entity", lights)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Requests = %d, want 1", n)
+	}
+
+	// Invalidated entities are fetched again
+	cache.Invalidate("light.kitchen")
+	if _, err := cache.GetState(ctx, "light.kitchen"); err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Requests = %d, want 2", n)
+	}
+}
+
+func TestStateCacheInvalidateBeforeGetStates(t *testing.T) {
+	var requests atomic.Int32
+	var state atomic.Value
+	state.Store("off")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`[
+			{"entity_id": "light.kitchen", "state": "` + state.Load().(string) + `"},
+			{"entity_id": "input_boolean.pause", "state": "off"}
+		]`))
+	}))
+	defer server.Close()
+
+	cache := NewStateCache(NewClient(server.URL, "test_token"), nil, time.Minute)
+	ctx := context.Background()
+
+	if _, err := cache.GetStates(ctx); err != nil {
+		t.Fatalf("GetStates() error = %v", err)
+	}
+
+	// A service call turned the light on
+	state.Store("on")
+	cache.Invalidate("light.kitchen")
+
+	entities, err := cache.GetStates(ctx)
+	if err != nil {
+		t.Fatalf("GetStates() error = %v", err)
+	}
+	if len(entities) != 2 || entities[1].EntityID != "light.kitchen" || entities[1].State != "on" {
+		t.Errorf("GetStates() after Invalidate = %v, want fresh light.kitchen", entities)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Requests = %d, want 2", n)
+	}
+
+	// Served from the refreshed snapshot again
+	if _, err := cache.GetStates(ctx); err != nil {
+		t.Fatalf("GetStates() error = %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Requests = %d, want 2", n)
+	}
+}
+
+func TestStateCacheExpires(t *testing.T) {
+	var requests atomic.Int32
+	server := newStatesServer("off", &requests)
+	defer server.Close()
+
+	cache := NewStateCache(NewClient(server.URL, "test_token"), nil, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetState(ctx, "light.kitchen"); err != nil {
+			t.Fatalf("GetState() error = %v", err)
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Requests = %d, want 2", n)
+	}
+
+	if _, err := cache.GetState(ctx, "light.unknown"); err == nil {
+		t.Error("GetState() of a missing entity should fail")
+	}
+}
+
+func TestStateCacheLiveStates(t *testing.T) {
+	var requests atomic.Int32
+	server := newStatesServer("off", &requests)
+	defer server.Close()
+
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		if msg["type"] != MsgTypeSubscribeEntities {
+			send(result(msg["id"], true, nil))
+			return
+		}
+		send(result(msg["id"], true, nil))
+		send(map[string]interface{}{
+			"id":   msg["id"],
+			"type": MsgTypeEvent,
+			"event": map[string]interface{}{
+				"a": map[string]interface{}{
+					"input_boolean.pause": map[string]interface{}{"s": "on", "lc": 1767520800.0},
+				},
+			},
+		})
+	})
+	defer m.close()
+
+	ws, stop := connectTestClient(t, m)
+	cache := NewStateCache(NewClient(server.URL, "test_token"), ws, time.Minute)
+	cache.Track("input_boolean.pause")
+
+	if err := ws.SubscribeStateChanges(context.Background()); err != nil {
+		t.Fatalf("SubscribeStateChanges() error = %v", err)
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(time.Second)
+	for {
+		entity, err := cache.GetState(ctx, "input_boolean.pause")
+		if err != nil {
+			t.Fatalf("GetState() error = %v", err)
+		}
+		if entity.State == "on" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("live state from the WebSocket was not served")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entities, err := cache.GetStates(ctx)
+	if err != nil {
+		t.Fatalf("GetStates() error = %v", err)
+	}
+	for _, e := range entities {
+		if e.EntityID == "input_boolean.pause" && e.State != "on" {
+			t.Errorf("GetStates() pause state = %v, want live state on", e.State)
+		}
+	}
+
+	// With the socket down, reads fall back to REST
+	stop()
+	entity, err := cache.GetState(ctx, "input_boolean.pause")
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if entity.State != "off" {
+		t.Errorf("State after disconnect = %v, want off from REST", entity.State)
+	}
+}
@@ -0,0 +1,165 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// CalendarEvent represents an event returned by the calendar API
+type CalendarEvent struct {
+	Summary     string            `json:"summary"`
+	Description string            `json:"description"`
+	Location    string            `json:"location"`
+	Start       CalendarEventTime `json:"start"`
+	End         CalendarEventTime `json:"end"`
+}
+
+// CalendarEventTime holds either a date-time or an all-day date
+type CalendarEventTime struct {
+	DateTime string `json:"dateTime,omitempty"`
+	Date     string `json:"date,omitempty"`
+}
+
+// Time parses the event time; all-day dates are interpreted in loc
+func (t CalendarEventTime) Time(loc *time.Location) (time.Time, error) {
+	if t.DateTime != "" {
+		parsed, err := time.Parse(time.RFC3339, t.DateTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse dateTime '%s': %w", t.DateTime, err)
+		}
+		return parsed, nil
+	}
+
+	if t.Date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", t.Date, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse date '%s': %w", t.Date, err)
+		}
+		return parsed, nil
+	}
+
+	return time.Time{}, fmt.Errorf("event time is empty")
+}
+
+// GetCalendarEvents gets events of a calendar entity within [start, end)
+func (c *Client) GetCalendarEvents(ctx context.Context, entityID string, start, end time.Time) ([]CalendarEvent, error) {
+	query := url.Values{}
+	query.Set("start", start.Format(time.RFC3339))
+	query.Set("end", end.Format(time.RFC3339))
+
+	reqURL := fmt.Sprintf("%s/calendars/%s?%s", c.baseURL, entityID, query.Encode())
+	resp, err := c.get(ctx, reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("calendar %s %w", entityID, ErrNotFound)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var events []CalendarEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return events, nil
+}
+
+// outageWindow is a scheduled outage derived from calendar events
+type outageWindow struct {
+	start, end time.Time
+}
+
+// outageWindows treats every calendar event as a scheduled outage and returns
+// them sorted, with overlapping or adjacent events merged into one outage
+func outageWindows(events []CalendarEvent, loc *time.Location) []outageWindow {
+	var windows []outageWindow
+	for _, e := range events {
+		start, err := e.Start.Time(loc)
+		if err != nil {
+			continue
+		}
+		end, err := e.End.Time(loc)
+		if err != nil || !end.After(start) {
+			continue
+		}
+		windows = append(windows, outageWindow{start: start, end: end})
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].start.Before(windows[j].start)
+	})
+
+	// Merge overlapping and adjacent windows
+	var merged []outageWindow
+	for _, w := range windows {
+		if n := len(merged); n > 0 && !w.start.After(merged[n-1].end) {
+			if w.end.After(merged[n-1].end) {
+				merged[n-1].end = w.end
+			}
+			continue
+		}
+		merged = append(merged, w)
+	}
+
+	return merged
+}
+
+// NextOutageWindow returns the next power off and power on times relative to
+// now from the scheduled outages in events. If now falls inside an outage,
+// nextOff is the start of the following outage (if any).
+func NextOutageWindow(events []CalendarEvent, now time.Time, loc *time.Location) (nextOff, nextOn *time.Time) {
+	merged := outageWindows(events, loc)
+
+	for _, w := range merged {
+		if !w.end.After(now) {
+			continue
+		}
+
+		if nextOn == nil {
+			end := w.end
+			nextOn = &end
+			if w.start.After(now) {
+				start := w.start
+				nextOff = &start
+				return nextOff, nextOn
+			}
+			continue
+		}
+
+		// Currently inside an outage, this is the following one
+		start := w.start
+		nextOff = &start
+		return nextOff, nextOn
+	}
+
+	return nextOff, nextOn
+}
+
+// ScheduledOffTime returns the total scheduled outage time within [from, to)
+func ScheduledOffTime(events []CalendarEvent, from, to time.Time, loc *time.Location) time.Duration {
+	var total time.Duration
+	for _, w := range outageWindows(events, loc) {
+		start, end := w.start, w.end
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
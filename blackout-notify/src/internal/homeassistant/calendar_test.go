@@ -0,0 +1,172 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetCalendarEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/calendars/calendar.outages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("start") == "" || r.URL.Query().Get("end") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		events := []CalendarEvent{
+			{
+				Summary: "Outage",
+				Start:   CalendarEventTime{DateTime: "2026-01-04T14:00:00+02:00"},
+				End:     CalendarEventTime{DateTime: "2026-01-04T18:00:00+02:00"},
+			},
+		}
+		if err := json.NewEncoder(w).Encode(events); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+	ctx := context.Background()
+	now := time.Now()
+
+	events, err := client.GetCalendarEvents(ctx, "calendar.outages", now, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCalendarEvents() error = %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("len(events) = %d, want 1", len(events))
+	}
+
+	if events[0].Summary != "Outage" {
+		t.Errorf("Summary = %v, want Outage", events[0].Summary)
+	}
+
+	_, err = client.GetCalendarEvents(ctx, "calendar.nonexistent", now, now.Add(24*time.Hour))
+	if err == nil {
+		t.Error("GetCalendarEvents() expected error for nonexistent calendar")
+	}
+}
+
+func TestNextOutageWindow(t *testing.T) {
+	loc := time.UTC
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 4, hour, minute, 0, 0, loc)
+	}
+	event := func(start, end time.Time) CalendarEvent {
+		return CalendarEvent{
+			Start: CalendarEventTime{DateTime: start.Format(time.RFC3339)},
+			End:   CalendarEventTime{DateTime: end.Format(time.RFC3339)},
+		}
+	}
+
+	events := []CalendarEvent{
+		event(at(20, 0), at(22, 0)),
+		event(at(10, 0), at(12, 0)),
+		event(at(12, 0), at(13, 30)), // adjacent to previous, merged
+		{Start: CalendarEventTime{DateTime: "invalid"}, End: CalendarEventTime{DateTime: "invalid"}},
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantOff *time.Time
+		wantOn  *time.Time
+	}{
+		{"before first outage", at(8, 0), timePtr(at(10, 0)), timePtr(at(13, 30))},
+		{"inside merged outage", at(12, 15), timePtr(at(20, 0)), timePtr(at(13, 30))},
+		{"between outages", at(15, 0), timePtr(at(20, 0)), timePtr(at(22, 0))},
+		{"inside last outage", at(21, 0), nil, timePtr(at(22, 0))},
+		{"after all outages", at(23, 0), nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOff, gotOn := NextOutageWindow(events, tt.now, loc)
+			if !sameTime(gotOff, tt.wantOff) {
+				t.Errorf("nextOff = %v, want %v", gotOff, tt.wantOff)
+			}
+			if !sameTime(gotOn, tt.wantOn) {
+				t.Errorf("nextOn = %v, want %v", gotOn, tt.wantOn)
+			}
+		})
+	}
+}
+
+func TestScheduledOffTime(t *testing.T) {
+	loc := time.UTC
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 4, hour, minute, 0, 0, loc)
+	}
+	event := func(start, end time.Time) CalendarEvent {
+		return CalendarEvent{
+			Start: CalendarEventTime{DateTime: start.Format(time.RFC3339)},
+			End:   CalendarEventTime{DateTime: end.Format(time.RFC3339)},
+		}
+	}
+
+	events := []CalendarEvent{
+		event(at(10, 0), at(12, 0)),
+		event(at(11, 0), at(13, 0)), // overlaps previous, counted once
+		event(at(20, 0), at(22, 0)),
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     time.Duration
+	}{
+		{"whole day", at(0, 0), at(23, 59), 5 * time.Hour},
+		{"clipped at both ends", at(12, 0), at(21, 0), 2 * time.Hour},
+		{"no outages", at(14, 0), at(19, 0), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScheduledOffTime(events, tt.from, tt.to, loc); got != tt.want {
+				t.Errorf("ScheduledOffTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarEventTimeAllDay(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	got, err := CalendarEventTime{Date: "2026-01-04"}.Time(loc)
+	if err != nil {
+		t.Fatalf("Time() error = %v", err)
+	}
+
+	want := time.Date(2026, 1, 4, 0, 0, 0, 0, loc)
+	if !got.Equal(want) {
+		t.Errorf("Time() = %v, want %v", got, want)
+	}
+
+	if _, err := (CalendarEventTime{}).Time(loc); err == nil {
+		t.Error("Time() expected error for empty event time")
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -15,6 +14,7 @@ type Client struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+	retry      RetryPolicy
 }
 
 // Entity represents a Home Assistant entity
@@ -31,9 +31,10 @@ type HAStatus struct {
 	Message string `json:"message"`
 }
 
-// ServiceCall represents a service call payload
-type ServiceCall struct {
-	EntityID string `json:"entity_id"`
+// StateUpdate represents a state write payload
+type StateUpdate struct {
+	State      string                 `json:"state"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 // NewClient creates a new Home Assistant client
@@ -44,26 +45,20 @@ func NewClient(baseURL, token string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retry: DefaultRetryPolicy,
 	}
 }
 
 // CheckConnection checks connection to Home Assistant
 func (c *Client) CheckConnection(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/", nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	c.setAuthHeader(req)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, c.baseURL+"/")
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return newAPIError(resp)
 	}
 
 	return nil
@@ -71,22 +66,14 @@ func (c *Client) CheckConnection(ctx context.Context) error {
 
 // GetStates gets all entity states
 func (c *Client) GetStates(ctx context.Context) ([]Entity, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/states", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	c.setAuthHeader(req)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, c.baseURL+"/states")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get states: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	var entities []Entity
@@ -99,8 +86,46 @@ func (c *Client) GetStates(ctx context.Context) ([]Entity, error) {
 
 // GetState gets state of a specific entity
 func (c *Client) GetState(ctx context.Context, entityID string) (*Entity, error) {
+	resp, err := c.get(ctx, fmt.Sprintf("%s/states/%s", c.baseURL, entityID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("entity %s %w", entityID, ErrNotFound)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var entity Entity
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &entity, nil
+}
+
+// CallService calls a Home Assistant service for a single entity
+func (c *Client) CallService(ctx context.Context, domain, service, entityID string) error {
+	_, err := c.CallServiceWithData(ctx, domain, service, EntityTarget(entityID), nil)
+	return err
+}
+
+// SetState creates or updates the state of an entity.
+// States written this way are not backed by an integration and disappear on HA restart.
+func (c *Client) SetState(ctx context.Context, entityID, state string, attributes map[string]interface{}) (*Entity, error) {
 	url := fmt.Sprintf("%s/states/%s", c.baseURL, entityID)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+
+	payload := StateUpdate{State: state, Attributes: attributes}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -109,17 +134,12 @@ func (c *Client) GetState(ctx context.Context, entityID string) (*Entity, error)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state: %w", err)
+		return nil, unavailableError("failed to set state", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("entity not found: %s", entityID)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError(resp)
 	}
 
 	var entity Entity
@@ -130,12 +150,11 @@ func (c *Client) GetState(ctx context.Context, entityID string) (*Entity, error)
 	return &entity, nil
 }
 
-// CallService calls a Home Assistant service
-func (c *Client) CallService(ctx context.Context, domain, service, entityID string) error {
-	url := fmt.Sprintf("%s/services/%s/%s", c.baseURL, domain, service)
+// FireEvent fires a custom event on the Home Assistant event bus
+func (c *Client) FireEvent(ctx context.Context, eventType string, data map[string]interface{}) error {
+	url := fmt.Sprintf("%s/events/%s", c.baseURL, eventType)
 
-	payload := ServiceCall{EntityID: entityID}
-	jsonData, err := json.Marshal(payload)
+	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
@@ -146,17 +165,15 @@ func (c *Client) CallService(ctx context.Context, domain, service, entityID stri
 	}
 
 	c.setAuthHeader(req)
-	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to call service: %w", err)
+		return unavailableError("failed to fire event", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("service call failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError(resp)
 	}
 
 	return nil
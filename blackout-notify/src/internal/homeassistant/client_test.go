@@ -3,6 +3,7 @@ package homeassistant
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -68,8 +69,14 @@ func TestGetState(t *testing.T) {
 
 	// Test entity not found
 	_, err = client.GetState(ctx, "light.nonexistent")
-	if err == nil {
-		t.Error("GetState() expected error for nonexistent entity")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetState() error = %v, want ErrNotFound", err)
+	}
+
+	// Test wrong token
+	_, err = NewClient(server.URL, "wrong_token").GetState(ctx, "light.test")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("GetState() error = %v, want ErrUnauthorized", err)
 	}
 }
 
@@ -122,3 +129,80 @@ func TestCallService(t *testing.T) {
 		t.Errorf("CallService() error = %v", err)
 	}
 }
+
+func TestSetState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/states/sensor.test" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var update StateUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		entity := Entity{EntityID: "sensor.test", State: update.State, Attributes: update.Attributes}
+		if err := json.NewEncoder(w).Encode(entity); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+
+	entity, err := client.SetState(context.Background(), "sensor.test", "42", map[string]interface{}{
+		"unit_of_measurement": "min",
+	})
+	if err != nil {
+		t.Fatalf("SetState() error = %v", err)
+	}
+
+	if entity.State != "42" {
+		t.Errorf("State = %v, want 42", entity.State)
+	}
+
+	if entity.Attributes["unit_of_measurement"] != "min" {
+		t.Errorf("unit_of_measurement = %v, want min", entity.Attributes["unit_of_measurement"])
+	}
+}
+
+func TestFireEvent(t *testing.T) {
+	var received map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/events/blackout_notify_power_changed" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err := w.Write([]byte(`{"message": "Event blackout_notify_power_changed fired."}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+
+	err := client.FireEvent(context.Background(), "blackout_notify_power_changed", map[string]interface{}{
+		"state": "off",
+	})
+	if err != nil {
+		t.Fatalf("FireEvent() error = %v", err)
+	}
+
+	if received["state"] != "off" {
+		t.Errorf("event data state = %v, want off", received["state"])
+	}
+
+	if err := client.FireEvent(context.Background(), "unknown_event", nil); err == nil {
+		t.Error("FireEvent() expected error for unexpected status")
+	}
+}
@@ -0,0 +1,169 @@
+package homeassistant
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// ErrAuthFailed is returned by Connect when Home Assistant rejects the token
+var ErrAuthFailed = errors.New("authentication failed: invalid token")
+
+// ConnectionEventType describes a connection lifecycle change
+type ConnectionEventType string
+
+const (
+	ConnEventConnected    ConnectionEventType = "connected"    // Connection established and authenticated
+	ConnEventDisconnected ConnectionEventType = "disconnected" // Established connection was lost
+	ConnEventAuthFailed   ConnectionEventType = "auth_failed"  // Home Assistant rejected the token
+	ConnEventResubscribed ConnectionEventType = "resubscribed" // State subscription restored after a reconnect
+)
+
+// reconnectJitter is the relative random spread applied to reconnect delays
+const reconnectJitter = 0.2
+
+// ConnectionEvent is passed to connection listeners
+type ConnectionEvent struct {
+	Type    ConnectionEventType
+	At      time.Time
+	Err     error // Cause of disconnected / auth_failed events
+	Attempt int   // Failed connection attempts since the last successful one
+}
+
+// ConnectionListener is a callback for connection lifecycle events.
+// Listeners run on the reconnect loop and must not block.
+type ConnectionListener func(event ConnectionEvent)
+
+// OnConnectionEvent registers a listener for connection lifecycle events
+func (c *WSClient) OnConnectionEvent(listener ConnectionListener) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.connListeners = append(c.connListeners, listener)
+}
+
+// emit notifies connection listeners
+func (c *WSClient) emit(eventType ConnectionEventType, err error, attempt int) {
+	c.handlersMu.RLock()
+	listeners := c.connListeners
+	c.handlersMu.RUnlock()
+
+	event := ConnectionEvent{Type: eventType, At: time.Now(), Err: err, Attempt: attempt}
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// RunWithReconnect keeps the client connected and subscribed until ctx is
+// cancelled or Stop is called, reconnecting with jittered exponential backoff.
+// Returns nil on shutdown.
+func (c *WSClient) RunWithReconnect(ctx context.Context) error {
+	delay := c.reconnectDelay
+	attempt := 0
+	wasConnected := false
+
+	for {
+		if c.shuttingDown(ctx) {
+			return nil
+		}
+
+		// Connect
+		if err := c.Connect(ctx); err != nil {
+			if c.shuttingDown(ctx) {
+				return nil
+			}
+
+			attempt++
+			if errors.Is(err, ErrAuthFailed) {
+				c.emit(ConnEventAuthFailed, err, attempt)
+			}
+
+			wait := withJitter(delay)
+			logger.Error("WebSocket connection failed: %v, retrying in %v", err, wait.Round(time.Second))
+			if !c.sleep(ctx, wait) {
+				return nil
+			}
+			delay = min(delay*2, c.maxReconnect)
+			continue
+		}
+
+		// Reset backoff on successful connection
+		delay = c.reconnectDelay
+		attempt = 0
+		reconnected := wasConnected
+		wasConnected = true
+		c.emit(ConnEventConnected, nil, 0)
+
+		// Start the reader so subscription results can be received
+		listenErr := make(chan error, 1)
+		go func() {
+			listenErr <- c.Listen(ctx)
+		}()
+
+		// Subscribe to events
+		if err := c.SubscribeStateChanges(ctx); err != nil {
+			c.Close()
+			<-listenErr
+			if c.shuttingDown(ctx) {
+				return nil
+			}
+
+			logger.Error("Failed to subscribe: %v", err)
+			c.emit(ConnEventDisconnected, err, 0)
+			if !c.sleep(ctx, withJitter(delay)) {
+				return nil
+			}
+			continue
+		}
+
+		if reconnected {
+			c.emit(ConnEventResubscribed, nil, 0)
+		}
+
+		// Wait until the connection fails
+		err := <-listenErr
+		c.Close()
+		if c.shuttingDown(ctx) {
+			return nil
+		}
+
+		logger.Error("WebSocket listen error: %v, reconnecting...", err)
+		c.emit(ConnEventDisconnected, err, 0)
+	}
+}
+
+// shuttingDown reports whether ctx is cancelled or Stop was called
+func (c *WSClient) shuttingDown(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-c.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d; returns false if interrupted by shutdown
+func (c *WSClient) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c.stopChan:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// withJitter spreads d randomly by ±reconnectJitter so add-ons restarted
+// together don't reconnect in lockstep
+func withJitter(d time.Duration) time.Duration {
+	spread := float64(d) * reconnectJitter
+	return d + time.Duration((rand.Float64()*2-1)*spread)
+}
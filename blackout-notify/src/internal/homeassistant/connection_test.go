@@ -0,0 +1,134 @@
+package homeassistant
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunWithReconnectLifecycle(t *testing.T) {
+	subscribed := make(chan struct{}, 4)
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		if msg["type"] == MsgTypeSubscribeEntities {
+			send(result(msg["id"], true, nil))
+			subscribed <- struct{}{}
+		}
+	})
+	defer m.close()
+
+	client := NewWSClient(m.server.URL+"/api", "test_token")
+	client.reconnectDelay = 10 * time.Millisecond
+	client.OnStateChange("sensor.power", func(string, *Entity, *Entity) {})
+
+	events := make(chan ConnectionEventType, 10)
+	client.OnConnectionEvent(func(event ConnectionEvent) {
+		events <- event.Type
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.RunWithReconnect(ctx)
+	}()
+
+	waitFor := func(ch <-chan struct{}) {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for subscription")
+		}
+	}
+
+	waitFor(subscribed)
+	client.Close() // Simulate a dropped connection
+	waitFor(subscribed)
+
+	want := []ConnectionEventType{ConnEventConnected, ConnEventDisconnected, ConnEventConnected, ConnEventResubscribed}
+	for _, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Fatalf("Connection event = %s, want %s", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s event", w)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunWithReconnect() error = %v, want nil on shutdown", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunWithReconnect() did not return after cancellation")
+	}
+}
+
+func TestRunWithReconnectCancelDuringBackoff(t *testing.T) {
+	m := newMockHA(t, func(map[string]interface{}, func(v interface{})) {})
+	url := m.server.URL
+	m.close() // Nothing listens anymore
+
+	client := NewWSClient(url+"/api", "test_token")
+	client.reconnectDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.RunWithReconnect(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunWithReconnect() error = %v, want nil on shutdown", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunWithReconnect() ignored cancellation while waiting to reconnect")
+	}
+}
+
+func TestRunWithReconnectAuthFailed(t *testing.T) {
+	m := newMockHA(t, func(map[string]interface{}, func(v interface{})) {})
+	defer m.close()
+
+	client := NewWSClient(m.server.URL+"/api", "wrong_token")
+	client.reconnectDelay = time.Hour
+
+	authFailed := make(chan ConnectionEvent, 1)
+	client.OnConnectionEvent(func(event ConnectionEvent) {
+		if event.Type == ConnEventAuthFailed {
+			authFailed <- event
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = client.RunWithReconnect(ctx)
+	}()
+
+	select {
+	case event := <-authFailed:
+		if event.Attempt != 1 {
+			t.Errorf("Attempt = %d, want 1", event.Attempt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("No auth_failed event")
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	base := 10 * time.Second
+	for i := 0; i < 100; i++ {
+		d := withJitter(base)
+		if d < 8*time.Second || d > 12*time.Second {
+			t.Fatalf("withJitter(%v) = %v, want within ±20%%", base, d)
+		}
+	}
+}
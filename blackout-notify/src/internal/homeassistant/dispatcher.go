@@ -0,0 +1,128 @@
+package homeassistant
+
+import (
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// defaultQueueSize is how many state changes may wait per handler key
+const defaultQueueSize = 64
+
+// DispatchStats reports state change delivery counters
+type DispatchStats struct {
+	Delivered uint64 // Handler invocations completed
+	Dropped   uint64 // State changes discarded because a queue was full
+	Panics    uint64 // Handler invocations that panicked
+}
+
+// stateChange is a queued state change for one handler key
+type stateChange struct {
+	entityID string
+	oldState *Entity
+	newState *Entity
+	handlers []StateChangeHandler
+}
+
+// dispatcher delivers state changes to handlers through one bounded queue and
+// worker goroutine per handler key (entity ID or "*"), so changes of an entity
+// are handled in order and a slow handler only delays its own key
+type dispatcher struct {
+	mu        sync.Mutex
+	queues    map[string]chan stateChange
+	queueSize int
+	stopped   bool
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	panics    atomic.Uint64
+}
+
+// newDispatcher creates a dispatcher with queues of the given size
+func newDispatcher(queueSize int) *dispatcher {
+	return &dispatcher{
+		queues:    make(map[string]chan stateChange),
+		queueSize: queueSize,
+	}
+}
+
+// enqueue queues a state change for the handlers of key without blocking.
+// When the queue is full the oldest pending change is dropped: the latest
+// state matters more than intermediate ones.
+func (d *dispatcher) enqueue(key string, change stateChange) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+
+	queue, ok := d.queues[key]
+	if !ok {
+		queue = make(chan stateChange, d.queueSize)
+		d.queues[key] = queue
+		go d.worker(key, queue)
+	}
+
+	for {
+		select {
+		case queue <- change:
+			return
+		default:
+		}
+
+		select {
+		case dropped := <-queue:
+			d.dropped.Add(1)
+			logger.Warn("Event queue for %s is full, dropping state change of %s", key, dropped.entityID)
+		default:
+		}
+	}
+}
+
+// worker runs queued state changes of one key sequentially
+func (d *dispatcher) worker(key string, queue <-chan stateChange) {
+	for change := range queue {
+		for _, h := range change.handlers {
+			d.invoke(key, h, change)
+		}
+	}
+}
+
+// invoke calls a single handler, recovering from panics
+func (d *dispatcher) invoke(key string, h StateChangeHandler, change stateChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.panics.Add(1)
+			logger.Error("State change handler for %s panicked: %v\n%s", key, r, debug.Stack())
+		}
+	}()
+
+	h(change.entityID, change.oldState, change.newState)
+	d.delivered.Add(1)
+}
+
+// stop closes all queues; workers exit after draining pending changes
+func (d *dispatcher) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+}
+
+// stats returns current counters
+func (d *dispatcher) stats() DispatchStats {
+	return DispatchStats{
+		Delivered: d.delivered.Load(),
+		Dropped:   d.dropped.Load(),
+		Panics:    d.panics.Load(),
+	}
+}
@@ -0,0 +1,126 @@
+package homeassistant
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcherOrdering(t *testing.T) {
+	d := newDispatcher(defaultQueueSize)
+	defer d.stop()
+
+	var mu sync.Mutex
+	var got []string
+	done := make(chan struct{})
+
+	handler := func(entityID string, oldState, newState *Entity) {
+		// Early events are slower; without ordering they would finish last
+		if newState.State == "0" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		got = append(got, newState.State)
+		if len(got) == 10 {
+			close(done)
+		}
+		mu.Unlock()
+	}
+
+	for i := 0; i < 10; i++ {
+		d.enqueue("sensor.power", stateChange{
+			entityID: "sensor.power",
+			newState: &Entity{State: fmt.Sprint(i)},
+			handlers: []StateChangeHandler{handler},
+		})
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handlers were not called")
+	}
+
+	for i, state := range got {
+		if state != fmt.Sprint(i) {
+			t.Fatalf("Handled states = %v, want ascending order", got)
+		}
+	}
+	if stats := d.stats(); stats.Delivered != 10 {
+		t.Errorf("Delivered = %d, want 10", stats.Delivered)
+	}
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	d := newDispatcher(2)
+	defer d.stop()
+
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	handler := func(entityID string, oldState, newState *Entity) {
+		<-release
+		handled <- newState.State
+	}
+
+	enqueue := func(state string) {
+		d.enqueue("sensor.power", stateChange{
+			entityID: "sensor.power",
+			newState: &Entity{State: state},
+			handlers: []StateChangeHandler{handler},
+		})
+	}
+
+	// First change blocks the worker, the next two fill the queue
+	enqueue("first")
+	time.Sleep(20 * time.Millisecond)
+	enqueue("a")
+	enqueue("b")
+	enqueue("latest") // drops "a"
+
+	close(release)
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case state := <-handled:
+			got = append(got, state)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Handled states = %v, want 3", got)
+		}
+	}
+
+	if got[2] != "latest" || got[1] != "b" {
+		t.Errorf("Handled states = %v, want [first b latest]", got)
+	}
+	if stats := d.stats(); stats.Dropped != 1 {
+		t.Errorf("Dropped = %d, want 1", stats.Dropped)
+	}
+}
+
+func TestDispatcherRecoversPanics(t *testing.T) {
+	d := newDispatcher(defaultQueueSize)
+	defer d.stop()
+
+	done := make(chan struct{})
+	handlers := []StateChangeHandler{
+		func(entityID string, oldState, newState *Entity) {
+			panic("buggy handler")
+		},
+		func(entityID string, oldState, newState *Entity) {
+			close(done)
+		},
+	}
+
+	d.enqueue("*", stateChange{entityID: "light.kitchen", newState: &Entity{State: "on"}, handlers: handlers})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler after the panicking one was not called")
+	}
+
+	if stats := d.stats(); stats.Panics != 1 {
+		t.Errorf("Panics = %d, want 1", stats.Panics)
+	}
+}
@@ -0,0 +1,288 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// MsgTypeSubscribeEntities subscribes to compressed state updates of selected entities
+const MsgTypeSubscribeEntities = "subscribe_entities"
+
+// compressedState is the compact state format used by subscribe_entities
+type compressedState struct {
+	State       *string                `json:"s,omitempty"`
+	Attributes  map[string]interface{} `json:"a,omitempty"`
+	LastChanged *float64               `json:"lc,omitempty"`
+	LastUpdated *float64               `json:"lu,omitempty"`
+}
+
+// compressedDiff describes changes to a known entity state
+type compressedDiff struct {
+	Add    *compressedState `json:"+,omitempty"`
+	Remove *struct {
+		Attributes []string `json:"a,omitempty"`
+	} `json:"-,omitempty"`
+}
+
+// entitiesEvent is the event payload of a subscribe_entities subscription
+type entitiesEvent struct {
+	Added   map[string]compressedState `json:"a,omitempty"`
+	Changed map[string]compressedDiff  `json:"c,omitempty"`
+	Removed []string                   `json:"r,omitempty"`
+}
+
+// subscribedEntities returns the entity IDs to subscribe to, or nil if a
+// wildcard handler requires all entities
+func (c *WSClient) subscribedEntities() []string {
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+
+	if _, ok := c.handlers["*"]; ok {
+		return nil
+	}
+
+	ids := make([]string, 0, len(c.handlers)+len(c.tracked))
+	for id := range c.handlers {
+		ids = append(ids, id)
+	}
+	for id := range c.tracked {
+		if _, ok := c.handlers[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Track adds entities to the subscription without registering handlers,
+// so their current state is available from CachedState
+func (c *WSClient) Track(entityIDs ...string) {
+	c.handlersMu.Lock()
+	for _, id := range entityIDs {
+		if id != "" {
+			c.tracked[id] = struct{}{}
+		}
+	}
+	c.handlersMu.Unlock()
+
+	go c.refreshSubscription(context.Background())
+}
+
+// CachedState returns a copy of the live state of a subscribed entity.
+// ok is false if the entity isn't subscribed or the connection is down.
+func (c *WSClient) CachedState(entityID string) (entity *Entity, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.connected || c.entitiesSubID == 0 {
+		return nil, false
+	}
+	cached, ok := c.entityStates[entityID]
+	if !ok {
+		return nil, false
+	}
+	return cached.clone(), true
+}
+
+// SubscribeStateChanges subscribes to state changes of entities registered through
+// OnStateChange. Filtering happens server-side via subscribe_entities; the first
+// event carries the current state of every entity.
+func (c *WSClient) SubscribeStateChanges(ctx context.Context) error {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	return c.subscribeEntitiesLocked(ctx)
+}
+
+// subscribeEntitiesLocked (re)creates the entities subscription; caller must hold c.subMu
+func (c *WSClient) subscribeEntitiesLocked(ctx context.Context) error {
+	entityIDs := c.subscribedEntities()
+	if entityIDs != nil && len(entityIDs) == 0 {
+		logger.Debug("No state change handlers registered, skipping subscription")
+		return nil
+	}
+
+	msg := map[string]interface{}{"type": MsgTypeSubscribeEntities}
+	if entityIDs != nil {
+		msg["entity_ids"] = entityIDs
+	}
+
+	id, err := c.Subscribe(ctx, msg, c.handleEntitiesEvent)
+	if err != nil {
+		return fmt.Errorf("subscription failed: %w", err)
+	}
+
+	c.mu.Lock()
+	previousID := c.entitiesSubID
+	c.entitiesSubID = id
+	c.subscribedIDs = entityIDs
+	c.mu.Unlock()
+
+	if previousID != 0 {
+		if err := c.Unsubscribe(ctx, previousID); err != nil {
+			logger.Debug("Failed to unsubscribe previous entities subscription: %v", err)
+		}
+	}
+
+	if entityIDs == nil {
+		logger.Info("Subscribed to state changes of all entities")
+	} else {
+		logger.Info("Subscribed to state changes of %d entities", len(entityIDs))
+	}
+	return nil
+}
+
+// refreshSubscription resubscribes if the set of watched entities changed
+func (c *WSClient) refreshSubscription(ctx context.Context) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	c.mu.Lock()
+	active := c.entitiesSubID != 0 && c.conn != nil
+	current := c.subscribedIDs
+	c.mu.Unlock()
+
+	if !active || sameStrings(current, c.subscribedEntities()) {
+		return
+	}
+
+	if err := c.subscribeEntitiesLocked(ctx); err != nil {
+		logger.Warn("Failed to update state subscription: %v", err)
+	}
+}
+
+// handleEntitiesEvent applies a compressed entities event to the known states
+// and notifies handlers of every entity whose state changed
+func (c *WSClient) handleEntitiesEvent(raw json.RawMessage) {
+	var event entitiesEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		logger.Debug("Failed to decode entities event: %v", err)
+		return
+	}
+
+	type change struct {
+		entityID           string
+		oldState, newState *Entity
+	}
+	var changes []change
+
+	c.mu.Lock()
+	for entityID, full := range event.Added {
+		old := c.entityStates[entityID]
+		entity := &Entity{EntityID: entityID, Attributes: full.Attributes}
+		if entity.Attributes == nil {
+			entity.Attributes = map[string]interface{}{}
+		}
+		if full.State != nil {
+			entity.State = *full.State
+		}
+		if full.LastChanged != nil {
+			entity.LastChanged = formatTimestamp(*full.LastChanged)
+			entity.LastUpdated = entity.LastChanged
+		}
+		if full.LastUpdated != nil {
+			entity.LastUpdated = formatTimestamp(*full.LastUpdated)
+		}
+		c.entityStates[entityID] = entity
+
+		// Snapshot after (re)subscribing: only report entities that changed meanwhile
+		if old != nil && old.State == entity.State && old.LastChanged == entity.LastChanged {
+			continue
+		}
+		changes = append(changes, change{entityID, old, entity})
+	}
+
+	for entityID, diff := range event.Changed {
+		old := c.entityStates[entityID]
+		if old == nil {
+			continue
+		}
+		entity := applyDiff(old, diff)
+		c.entityStates[entityID] = entity
+		changes = append(changes, change{entityID, old, entity})
+	}
+
+	for _, entityID := range event.Removed {
+		if old, ok := c.entityStates[entityID]; ok {
+			delete(c.entityStates, entityID)
+			changes = append(changes, change{entityID, old, nil})
+		}
+	}
+	c.mu.Unlock()
+
+	for _, ch := range changes {
+		c.dispatchStateChange(ch.entityID, ch.oldState, ch.newState)
+	}
+}
+
+// clone returns a copy of the entity with its own attributes map
+func (e *Entity) clone() *Entity {
+	copied := *e
+	copied.Attributes = make(map[string]interface{}, len(e.Attributes))
+	for k, v := range e.Attributes {
+		copied.Attributes[k] = v
+	}
+	return &copied
+}
+
+// applyDiff returns a new entity with diff applied to old
+func applyDiff(old *Entity, diff compressedDiff) *Entity {
+	entity := &Entity{
+		EntityID:    old.EntityID,
+		State:       old.State,
+		Attributes:  make(map[string]interface{}, len(old.Attributes)),
+		LastChanged: old.LastChanged,
+		LastUpdated: old.LastUpdated,
+	}
+	for k, v := range old.Attributes {
+		entity.Attributes[k] = v
+	}
+
+	if add := diff.Add; add != nil {
+		if add.State != nil {
+			entity.State = *add.State
+		}
+		for k, v := range add.Attributes {
+			entity.Attributes[k] = v
+		}
+		if add.LastChanged != nil {
+			entity.LastChanged = formatTimestamp(*add.LastChanged)
+			entity.LastUpdated = entity.LastChanged
+		} else if add.LastUpdated != nil {
+			entity.LastUpdated = formatTimestamp(*add.LastUpdated)
+		}
+	}
+
+	if diff.Remove != nil {
+		for _, k := range diff.Remove.Attributes {
+			delete(entity.Attributes, k)
+		}
+	}
+
+	return entity
+}
+
+// formatTimestamp converts a Unix timestamp in seconds to RFC3339
+func formatTimestamp(ts float64) string {
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+}
+
+// sameStrings compares two sorted string slices; nil (all entities) differs from empty
+func sameStrings(a, b []string) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package homeassistant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors of the REST client; match with errors.Is
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUnavailable  = errors.New("home assistant unavailable")
+)
+
+// maxErrorBody limits how much of an error response is kept
+const maxErrorBody = 512
+
+// APIError is returned when Home Assistant answers with an unexpected status.
+// It unwraps to ErrNotFound, ErrUnauthorized or ErrUnavailable where applicable.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// Unwrap maps the status code to a sentinel error
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// Returned by the Supervisor proxy while Core is restarting
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// newAPIError builds an APIError from a response, reading part of its body
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+// unavailableError wraps a transport error (connection refused, timeout, ...)
+// so callers can treat it like a 502 from the proxy
+func unavailableError(op string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+}
+
+// IsTransient reports whether a request failed in a way worth retrying
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
@@ -0,0 +1,100 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HistoryOptions configures a history query
+type HistoryOptions struct {
+	EntityIDs              []string  // Entities to fetch (required by HA for efficient queries)
+	End                    time.Time // End of the period; zero means now
+	MinimalResponse        bool      // Only state and last_changed for all but the first and last state
+	NoAttributes           bool      // Skip attributes
+	SignificantChangesOnly bool      // Only state changes, not attribute updates
+}
+
+// LogbookEntry represents a single logbook record
+type LogbookEntry struct {
+	When          string `json:"when"`
+	Name          string `json:"name"`
+	Message       string `json:"message"`
+	EntityID      string `json:"entity_id"`
+	State         string `json:"state"`
+	Domain        string `json:"domain"`
+	ContextUserID string `json:"context_user_id"`
+}
+
+// GetHistory gets state history starting at start.
+// The result holds one list of states per entity, oldest first.
+func (c *Client) GetHistory(ctx context.Context, start time.Time, opts HistoryOptions) ([][]Entity, error) {
+	query := url.Values{}
+	if len(opts.EntityIDs) > 0 {
+		query.Set("filter_entity_id", strings.Join(opts.EntityIDs, ","))
+	}
+	if !opts.End.IsZero() {
+		query.Set("end_time", opts.End.Format(time.RFC3339))
+	}
+	if opts.MinimalResponse {
+		query.Set("minimal_response", "")
+	}
+	if opts.NoAttributes {
+		query.Set("no_attributes", "")
+	}
+	if opts.SignificantChangesOnly {
+		query.Set("significant_changes_only", "")
+	}
+
+	reqURL := fmt.Sprintf("%s/history/period/%s?%s", c.baseURL, url.PathEscape(start.Format(time.RFC3339)), query.Encode())
+
+	var history [][]Entity
+	if err := c.getJSON(ctx, reqURL, &history); err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	return history, nil
+}
+
+// GetLogbook gets logbook entries between start and end, optionally for a single entity
+func (c *Client) GetLogbook(ctx context.Context, start, end time.Time, entityID string) ([]LogbookEntry, error) {
+	query := url.Values{}
+	if !end.IsZero() {
+		query.Set("end_time", end.Format(time.RFC3339))
+	}
+	if entityID != "" {
+		query.Set("entity", entityID)
+	}
+
+	reqURL := fmt.Sprintf("%s/logbook/%s?%s", c.baseURL, url.PathEscape(start.Format(time.RFC3339)), query.Encode())
+
+	var entries []LogbookEntry
+	if err := c.getJSON(ctx, reqURL, &entries); err != nil {
+		return nil, fmt.Errorf("failed to get logbook: %w", err)
+	}
+
+	return entries, nil
+}
+
+// getJSON performs a GET request and decodes the JSON response into v
+func (c *Client) getJSON(ctx context.Context, reqURL string, v interface{}) error {
+	resp, err := c.get(ctx, reqURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/history/period/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("filter_entity_id") != "binary_sensor.power" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := r.URL.Query()["minimal_response"]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		history := [][]Entity{{
+			{EntityID: "binary_sensor.power", State: "on", LastChanged: "2026-01-04T08:00:00+00:00"},
+			{State: "off", LastChanged: "2026-01-04T10:00:00+00:00"},
+		}}
+		if err := json.NewEncoder(w).Encode(history); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+
+	history, err := client.GetHistory(context.Background(), time.Now().Add(-24*time.Hour), HistoryOptions{
+		EntityIDs:       []string{"binary_sensor.power"},
+		MinimalResponse: true,
+	})
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+
+	if len(history) != 1 || len(history[0]) != 2 {
+		t.Fatalf("history = %v, want one entity with 2 states", history)
+	}
+
+	if history[0][1].State != "off" {
+		t.Errorf("State = %v, want off", history[0][1].State)
+	}
+}
+
+func TestGetLogbook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/logbook/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		entries := []LogbookEntry{
+			{When: "2026-01-04T10:00:00+00:00", Name: "Power", State: "off", EntityID: r.URL.Query().Get("entity")},
+		}
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+	now := time.Now()
+
+	entries, err := client.GetLogbook(context.Background(), now.Add(-time.Hour), now, "binary_sensor.power")
+	if err != nil {
+		t.Fatalf("GetLogbook() error = %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].EntityID != "binary_sensor.power" {
+		t.Errorf("entries = %v, want one entry for binary_sensor.power", entries)
+	}
+}
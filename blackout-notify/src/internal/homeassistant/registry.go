@@ -0,0 +1,181 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Registry list commands of the WebSocket API
+const (
+	MsgTypeAreaRegistryList   = "config/area_registry/list"
+	MsgTypeDeviceRegistryList = "config/device_registry/list"
+	MsgTypeEntityRegistryList = "config/entity_registry/list"
+)
+
+// Area is an entry of the area registry
+type Area struct {
+	AreaID  string   `json:"area_id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Device is an entry of the device registry
+type Device struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	NameByUser string `json:"name_by_user"`
+	AreaID     string `json:"area_id"`
+	DisabledBy string `json:"disabled_by"`
+}
+
+// RegistryEntry is an entry of the entity registry. Entities without a
+// unique ID (e.g. from YAML) have states but no registry entry.
+type RegistryEntry struct {
+	EntityID   string `json:"entity_id"`
+	Name       string `json:"name"`
+	DeviceID   string `json:"device_id"`
+	AreaID     string `json:"area_id"`
+	DisabledBy string `json:"disabled_by"`
+	HiddenBy   string `json:"hidden_by"`
+}
+
+// Registry is a snapshot of the area, device and entity registries
+type Registry struct {
+	Areas    []Area
+	Devices  []Device
+	Entities []RegistryEntry
+}
+
+// ListAreas returns the area registry
+func (c *WSClient) ListAreas(ctx context.Context) ([]Area, error) {
+	var areas []Area
+	if err := c.listRegistry(ctx, MsgTypeAreaRegistryList, &areas); err != nil {
+		return nil, err
+	}
+	return areas, nil
+}
+
+// ListDevices returns the device registry
+func (c *WSClient) ListDevices(ctx context.Context) ([]Device, error) {
+	var devices []Device
+	if err := c.listRegistry(ctx, MsgTypeDeviceRegistryList, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// ListEntityRegistry returns the entity registry
+func (c *WSClient) ListEntityRegistry(ctx context.Context) ([]RegistryEntry, error) {
+	var entries []RegistryEntry
+	if err := c.listRegistry(ctx, MsgTypeEntityRegistryList, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetRegistry reads the area, device and entity registries
+func (c *WSClient) GetRegistry(ctx context.Context) (*Registry, error) {
+	areas, err := c.ListAreas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := c.ListEntityRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{Areas: areas, Devices: devices, Entities: entities}, nil
+}
+
+// listRegistry sends a registry list command and decodes its result into v
+func (c *WSClient) listRegistry(ctx context.Context, msgType string, v interface{}) error {
+	result, err := c.Call(ctx, map[string]interface{}{"type": msgType})
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(result, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", msgType, err)
+	}
+	return nil
+}
+
+// Area returns the area with the given ID
+func (r *Registry) Area(areaID string) (Area, bool) {
+	for _, a := range r.Areas {
+		if a.AreaID == areaID {
+			return a, true
+		}
+	}
+	return Area{}, false
+}
+
+// AreaEntities returns the enabled entities of an area, sorted. Like Home
+// Assistant, an entity belongs to its own area or, if it has none, to the
+// area of its device.
+func (r *Registry) AreaEntities(areaID string) []string {
+	deviceAreas := make(map[string]string, len(r.Devices))
+	for _, d := range r.Devices {
+		deviceAreas[d.ID] = d.AreaID
+	}
+
+	var ids []string
+	for _, e := range r.Entities {
+		if e.DisabledBy != "" {
+			continue
+		}
+		area := e.AreaID
+		if area == "" {
+			area = deviceAreas[e.DeviceID]
+		}
+		if area == areaID {
+			ids = append(ids, e.EntityID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DeviceEntities returns the enabled entities of a device, sorted
+func (r *Registry) DeviceEntities(deviceID string) []string {
+	var ids []string
+	for _, e := range r.Entities {
+		if e.DeviceID == deviceID && e.DisabledBy == "" {
+			ids = append(ids, e.EntityID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ExpandTarget returns the entities a service call target resolves to:
+// its entity IDs plus the entities of its areas and devices, sorted and unique
+func (r *Registry) ExpandTarget(target ServiceTarget) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(list []string) {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	add(target.EntityIDs)
+	for _, areaID := range target.AreaIDs {
+		add(r.AreaEntities(areaID))
+	}
+	for _, deviceID := range target.DeviceIDs {
+		add(r.DeviceEntities(deviceID))
+	}
+
+	sort.Strings(ids)
+	return ids
+}
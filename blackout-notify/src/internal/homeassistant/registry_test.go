@@ -0,0 +1,102 @@
+package homeassistant
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func testRegistry() *Registry {
+	return &Registry{
+		Areas: []Area{
+			{AreaID: "bedroom", Name: "Bedroom"},
+			{AreaID: "kitchen", Name: "Kitchen"},
+		},
+		Devices: []Device{
+			{ID: "plug", Name: "Fan plug", AreaID: "bedroom"},
+			{ID: "lamp", Name: "Lamp", AreaID: "kitchen"},
+		},
+		Entities: []RegistryEntry{
+			{EntityID: "switch.fan_plug", DeviceID: "plug"},
+			{EntityID: "sensor.fan_plug_power", DeviceID: "plug"},
+			{EntityID: "sensor.fan_plug_energy", DeviceID: "plug", DisabledBy: "integration"},
+			{EntityID: "light.lamp", DeviceID: "lamp"},
+			// Own area wins over the device area
+			{EntityID: "light.lamp_night", DeviceID: "lamp", AreaID: "bedroom"},
+			{EntityID: "light.ceiling", AreaID: "kitchen"},
+		},
+	}
+}
+
+func TestRegistryAreaEntities(t *testing.T) {
+	reg := testRegistry()
+
+	tests := []struct {
+		areaID string
+		want   []string
+	}{
+		{"bedroom", []string{"light.lamp_night", "sensor.fan_plug_power", "switch.fan_plug"}},
+		{"kitchen", []string{"light.ceiling", "light.lamp"}},
+		{"garage", nil},
+	}
+
+	for _, tt := range tests {
+		if got := reg.AreaEntities(tt.areaID); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AreaEntities(%s) = %v, want %v", tt.areaID, got, tt.want)
+		}
+	}
+
+	if area, ok := reg.Area("kitchen"); !ok || area.Name != "Kitchen" {
+		t.Errorf("Area(kitchen) = %v, %v", area, ok)
+	}
+}
+
+func TestRegistryExpandTarget(t *testing.T) {
+	reg := testRegistry()
+
+	target := ServiceTarget{
+		EntityIDs: []string{"light.ceiling", "switch.boiler"},
+		AreaIDs:   []string{"kitchen"},
+		DeviceIDs: []string{"plug"},
+	}
+	want := []string{"light.ceiling", "light.lamp", "sensor.fan_plug_power", "switch.boiler", "switch.fan_plug"}
+
+	if got := reg.ExpandTarget(target); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandTarget() = %v, want %v", got, want)
+	}
+}
+
+func TestWSGetRegistry(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		switch msg["type"] {
+		case MsgTypeAreaRegistryList:
+			send(result(msg["id"], true, []map[string]interface{}{
+				{"area_id": "kitchen", "name": "Kitchen", "aliases": []string{"кухня"}, "floor_id": nil},
+			}))
+		case MsgTypeDeviceRegistryList:
+			send(result(msg["id"], true, []map[string]interface{}{
+				{"id": "lamp", "name": "Lamp", "name_by_user": nil, "area_id": "kitchen", "disabled_by": nil},
+			}))
+		case MsgTypeEntityRegistryList:
+			send(result(msg["id"], true, []map[string]interface{}{
+				{"entity_id": "light.lamp", "device_id": "lamp", "area_id": nil, "disabled_by": nil, "hidden_by": nil},
+			}))
+		}
+	})
+	defer m.close()
+
+	client, stop := connectTestClient(t, m)
+	defer stop()
+
+	reg, err := client.GetRegistry(context.Background())
+	if err != nil {
+		t.Fatalf("GetRegistry() error = %v", err)
+	}
+
+	if len(reg.Areas) != 1 || reg.Areas[0].Name != "Kitchen" || reg.Areas[0].Aliases[0] != "кухня" {
+		t.Errorf("Areas = %+v", reg.Areas)
+	}
+	if got := reg.AreaEntities("kitchen"); !reflect.DeepEqual(got, []string{"light.lamp"}) {
+		t.Errorf("AreaEntities(kitchen) = %v, want [light.lamp]", got)
+	}
+}
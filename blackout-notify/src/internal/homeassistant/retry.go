@@ -0,0 +1,83 @@
+package homeassistant
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// RetryPolicy controls how idempotent GET requests are retried when Home
+// Assistant is temporarily unavailable (e.g. restarting)
+type RetryPolicy struct {
+	MaxAttempts  int           // Total attempts including the first one; 1 disables retries
+	InitialDelay time.Duration // Delay before the first retry, doubled for every next one
+	MaxDelay     time.Duration // Upper bound for the delay
+}
+
+// DefaultRetryPolicy rides out a short Core restart without delaying messages for long
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  3,
+	InitialDelay: time.Second,
+	MaxDelay:     5 * time.Second,
+}
+
+// SetRetryPolicy replaces the retry policy for GET requests
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	c.retry = policy
+}
+
+// get performs an authenticated GET request, retrying transient failures.
+// The caller must close the body of the returned response, which may have any status.
+func (c *Client) get(ctx context.Context, reqURL string) (*http.Response, error) {
+	delay := c.retry.InitialDelay
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.getOnce(ctx, reqURL)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.retry.MaxAttempts || !IsTransient(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		logger.Debug("GET %s failed (attempt %d/%d): %v, retrying in %v", reqURL, attempt, c.retry.MaxAttempts, err, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, c.retry.MaxDelay)
+	}
+}
+
+// getOnce performs a single GET request. Transient statuses are returned as
+// errors so they can be retried; other statuses are left to the caller.
+func (c *Client) getOnce(ctx context.Context, reqURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, unavailableError("request failed", err)
+	}
+
+	if apiErr := (&APIError{StatusCode: resp.StatusCode}); IsTransient(apiErr) {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
+}
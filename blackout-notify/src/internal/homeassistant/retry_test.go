@@ -0,0 +1,94 @@
+package homeassistant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetRetriesTransientFailures(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Core restarting behind the Supervisor proxy
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"entity_id": "sensor.next_power_on", "state": "2026-01-04T18:00:00+02:00"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	entity, err := client.GetState(context.Background(), "sensor.next_power_on")
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if entity.State != "2026-01-04T18:00:00+02:00" {
+		t.Errorf("State = %v", entity.State)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Requests = %d, want 3", n)
+	}
+}
+
+func TestGetGivesUpAfterMaxAttempts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	_, err := client.GetStates(context.Background())
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("GetStates() error = %v, want ErrUnavailable", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GetStates() error = %v, want APIError with status 503", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Requests = %d, want 2", n)
+	}
+}
+
+func TestGetDoesNotRetryPermanentErrors(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	if _, err := client.GetState(context.Background(), "sensor.missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetState() error = %v, want ErrNotFound", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Requests = %d, want 1", n)
+	}
+}
+
+func TestGetConnectionRefusedIsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client := NewClient(url, "test_token")
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	if err := client.CheckConnection(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("CheckConnection() error = %v, want ErrUnavailable", err)
+	}
+}
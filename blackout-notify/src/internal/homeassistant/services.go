@@ -0,0 +1,100 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EntityMatchAll is the entity ID Home Assistant expands to all entities of a service's domain
+const EntityMatchAll = "all"
+
+// targetFields are the service data keys that select the target of a service call
+var targetFields = []string{"entity_id", "area_id", "device_id", "floor_id", "label_id", "target"}
+
+// ServiceTarget selects what a service call acts on
+type ServiceTarget struct {
+	EntityIDs []string `json:"entity_id,omitempty"`
+	AreaIDs   []string `json:"area_id,omitempty"`
+	DeviceIDs []string `json:"device_id,omitempty"`
+}
+
+// IsEmpty reports whether no target is set
+func (t ServiceTarget) IsEmpty() bool {
+	return len(t.EntityIDs) == 0 && len(t.AreaIDs) == 0 && len(t.DeviceIDs) == 0
+}
+
+// TargetsAll reports whether the target selects every entity ("entity_id: all")
+func (t ServiceTarget) TargetsAll() bool {
+	for _, id := range t.EntityIDs {
+		if id == EntityMatchAll {
+			return true
+		}
+	}
+	return false
+}
+
+// EntityTarget creates a target for the given entity IDs
+func EntityTarget(entityIDs ...string) ServiceTarget {
+	return ServiceTarget{EntityIDs: entityIDs}
+}
+
+// CallServiceWithData calls a Home Assistant service with a target and arbitrary
+// service data, returning the states that changed while the service was running.
+// Target fields in data are rejected: callers check the target, not the data.
+func (c *Client) CallServiceWithData(ctx context.Context, domain, service string, target ServiceTarget, data map[string]interface{}) ([]Entity, error) {
+	for _, key := range targetFields {
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("service data must not contain %s, set the target instead", key)
+		}
+	}
+
+	url := fmt.Sprintf("%s/services/%s/%s", c.baseURL, domain, service)
+
+	// The REST API takes target fields alongside service data in a flat object
+	payload := make(map[string]interface{}, len(data)+3)
+	for k, v := range data {
+		payload[k] = v
+	}
+	if len(target.EntityIDs) > 0 {
+		payload["entity_id"] = target.EntityIDs
+	}
+	if len(target.AreaIDs) > 0 {
+		payload["area_id"] = target.AreaIDs
+	}
+	if len(target.DeviceIDs) > 0 {
+		payload["device_id"] = target.DeviceIDs
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, unavailableError("failed to call service", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("service call failed: %w", newAPIError(resp))
+	}
+
+	var changed []Entity
+	if err := json.NewDecoder(resp.Body).Decode(&changed); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return changed, nil
+}
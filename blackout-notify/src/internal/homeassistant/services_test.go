@@ -0,0 +1,104 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallServiceWithData(t *testing.T) {
+	var payload map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/services/light/turn_on" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		changed := []Entity{{EntityID: "light.kitchen", State: "on"}}
+		if err := json.NewEncoder(w).Encode(changed); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+
+	target := ServiceTarget{
+		EntityIDs: []string{"light.kitchen"},
+		AreaIDs:   []string{"kitchen"},
+	}
+	changed, err := client.CallServiceWithData(context.Background(), "light", "turn_on", target, map[string]interface{}{
+		"brightness_pct": 50,
+	})
+	if err != nil {
+		t.Fatalf("CallServiceWithData() error = %v", err)
+	}
+
+	if len(changed) != 1 || changed[0].EntityID != "light.kitchen" {
+		t.Errorf("changed = %v, want [light.kitchen]", changed)
+	}
+
+	if payload["brightness_pct"] != 50.0 {
+		t.Errorf("brightness_pct = %v, want 50", payload["brightness_pct"])
+	}
+
+	areas, ok := payload["area_id"].([]interface{})
+	if !ok || len(areas) != 1 || areas[0] != "kitchen" {
+		t.Errorf("area_id = %v, want [kitchen]", payload["area_id"])
+	}
+
+	if _, ok := payload["device_id"]; ok {
+		t.Error("device_id should be omitted when empty")
+	}
+}
+
+func TestServiceTargetIsEmpty(t *testing.T) {
+	if !(ServiceTarget{}).IsEmpty() {
+		t.Error("empty target should be empty")
+	}
+
+	if EntityTarget("light.kitchen").IsEmpty() {
+		t.Error("entity target should not be empty")
+	}
+}
+
+func TestCallServiceWithDataRejectsTargetInData(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+
+	for _, key := range []string{"entity_id", "area_id", "device_id", "target"} {
+		_, err := client.CallServiceWithData(context.Background(), "lock", "unlock", ServiceTarget{}, map[string]interface{}{
+			key: "lock.front_door",
+		})
+		if err == nil {
+			t.Errorf("CallServiceWithData() with %s in data should fail", key)
+		}
+	}
+
+	if called {
+		t.Error("service should not be called")
+	}
+}
+
+func TestServiceTargetTargetsAll(t *testing.T) {
+	if !EntityTarget("light.kitchen", EntityMatchAll).TargetsAll() {
+		t.Error("target with entity_id all should target all")
+	}
+
+	if EntityTarget("light.kitchen").TargetsAll() || (ServiceTarget{AreaIDs: []string{"all"}}).TargetsAll() {
+		t.Error("target without entity_id all should not target all")
+	}
+}
@@ -0,0 +1,49 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// TemplateRequest represents a template rendering payload
+type TemplateRequest struct {
+	Template  string                 `json:"template"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// RenderTemplate renders a Jinja template in Home Assistant.
+// Variables are available in the template by name.
+func (c *Client) RenderTemplate(ctx context.Context, template string, variables map[string]interface{}) (string, error) {
+	jsonData, err := json.Marshal(TemplateRequest{Template: template, Variables: variables})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/template", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", unavailableError("failed to render template", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("template rendering failed: %w", newAPIError(resp))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return string(body), nil
+}
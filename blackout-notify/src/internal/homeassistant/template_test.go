@@ -0,0 +1,54 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/template" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var req TemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if req.Template == "{{ invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			if _, err := w.Write([]byte(`{"message": "Error rendering template"}`)); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+			return
+		}
+
+		// Echo the variable to check it was passed
+		if _, err := w.Write([]byte("state=" + req.Variables["state"].(string))); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test_token")
+	ctx := context.Background()
+
+	got, err := client.RenderTemplate(ctx, "state={{ state }}", map[string]interface{}{"state": "off"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+
+	if got != "state=off" {
+		t.Errorf("RenderTemplate() = %q, want %q", got, "state=off")
+	}
+
+	if _, err := client.RenderTemplate(ctx, "{{ invalid", nil); err == nil {
+		t.Error("RenderTemplate() expected error for invalid template")
+	}
+}
@@ -3,6 +3,7 @@ package homeassistant
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -21,18 +22,37 @@ const (
 	MsgTypeAuthOK        = "auth_ok"
 	MsgTypeAuthInvalid   = "auth_invalid"
 	MsgTypeSubscribe     = "subscribe_events"
+	MsgTypeUnsubscribe   = "unsubscribe_events"
+	MsgTypeGetStates     = "get_states"
+	MsgTypeCallService   = "call_service"
+	MsgTypeRenderTpl     = "render_template"
 	MsgTypeEvent         = "event"
 	MsgTypeResult        = "result"
+	MsgTypePing          = "ping"
+	MsgTypePong          = "pong"
 	EventTypeStateChange = "state_changed"
 )
 
+// Timing defaults
+const (
+	defaultCommandTimeout = 10 * time.Second // How long Call waits for a result
+	defaultPingInterval   = 30 * time.Second // How often the connection is probed
+	defaultPongTimeout    = 10 * time.Second // How long to wait for a pong before reconnecting
+)
+
+// ErrNotConnected is returned when a command is sent without an active connection
+var ErrNotConnected = errors.New("not connected")
+
+// errConnectionClosed is delivered to pending commands when the connection drops
+var errConnectionClosed = errors.New("connection closed")
+
 // WSMessage represents a WebSocket message
 type WSMessage struct {
 	ID          int             `json:"id,omitempty"`
 	Type        string          `json:"type"`
 	AccessToken string          `json:"access_token,omitempty"`
 	EventType   string          `json:"event_type,omitempty"`
-	Event       *WSEvent        `json:"event,omitempty"`
+	Event       json.RawMessage `json:"event,omitempty"`
 	Success     bool            `json:"success,omitempty"`
 	Result      json.RawMessage `json:"result,omitempty"`
 	Error       *WSError        `json:"error,omitempty"`
@@ -44,6 +64,11 @@ type WSError struct {
 	Message string `json:"message"`
 }
 
+// Error implements the error interface
+func (e *WSError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
 // WSEvent represents a WebSocket event
 type WSEvent struct {
 	EventType string           `json:"event_type"`
@@ -62,15 +87,35 @@ type StateChangeData struct {
 // StateChangeHandler is a callback for state changes
 type StateChangeHandler func(entityID string, oldState, newState *Entity)
 
-// WSClient is a WebSocket client for Home Assistant
+// EventHandler receives raw event payloads of a subscription
+type EventHandler func(event json.RawMessage)
+
+// WSClient is a WebSocket client for Home Assistant.
+// A single reader (Listen) dispatches command results to waiting callers
+// by message ID and subscription events to their handlers.
 type WSClient struct {
 	baseURL        string
 	token          string
 	conn           *websocket.Conn
 	mu             sync.Mutex
+	writeMu        sync.Mutex
 	msgID          int
+	pending        map[int]chan *WSMessage
+	subscriptions  map[int]EventHandler
+	subMu          sync.Mutex
+	entitiesSubID  int
+	subscribedIDs  []string
+	entityStates   map[string]*Entity
 	handlers       map[string][]StateChangeHandler
+	tracked        map[string]struct{}
 	handlersMu     sync.RWMutex
+	dispatcher     *dispatcher
+	connListeners  []ConnectionListener
+	commandTimeout time.Duration
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
+	lastPong       time.Time
+	pongLatency    time.Duration
 	reconnectDelay time.Duration
 	maxReconnect   time.Duration
 	stopChan       chan struct{}
@@ -82,7 +127,15 @@ func NewWSClient(baseURL, token string) *WSClient {
 	return &WSClient{
 		baseURL:        baseURL,
 		token:          token,
+		pending:        make(map[int]chan *WSMessage),
+		subscriptions:  make(map[int]EventHandler),
+		entityStates:   make(map[string]*Entity),
 		handlers:       make(map[string][]StateChangeHandler),
+		tracked:        make(map[string]struct{}),
+		dispatcher:     newDispatcher(defaultQueueSize),
+		commandTimeout: defaultCommandTimeout,
+		pingInterval:   defaultPingInterval,
+		pongTimeout:    defaultPongTimeout,
 		reconnectDelay: 5 * time.Second,
 		maxReconnect:   5 * time.Minute,
 		stopChan:       make(chan struct{}),
@@ -130,10 +183,8 @@ func (c *WSClient) Connect(ctx context.Context) error {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
-	c.mu.Lock()
-	c.conn = conn
-	c.msgID = 0
-	c.mu.Unlock()
+	// The connection is published only after auth_ok, so that no command
+	// is written before or concurrently with the handshake
 
 	// Read auth_required message
 	var authReq WSMessage
@@ -152,7 +203,10 @@ func (c *WSClient) Connect(ctx context.Context) error {
 		Type:        MsgTypeAuth,
 		AccessToken: c.token,
 	}
-	if err := conn.WriteJSON(authMsg); err != nil {
+	c.writeMu.Lock()
+	err = conn.WriteJSON(authMsg)
+	c.writeMu.Unlock()
+	if err != nil {
 		conn.Close()
 		return fmt.Errorf("failed to send auth: %w", err)
 	}
@@ -166,7 +220,7 @@ func (c *WSClient) Connect(ctx context.Context) error {
 
 	if authResp.Type == MsgTypeAuthInvalid {
 		conn.Close()
-		return fmt.Errorf("authentication failed: invalid token")
+		return ErrAuthFailed
 	}
 
 	if authResp.Type != MsgTypeAuthOK {
@@ -175,6 +229,10 @@ func (c *WSClient) Connect(ctx context.Context) error {
 	}
 
 	c.mu.Lock()
+	c.conn = conn
+	c.msgID = 0
+	c.subscriptions = make(map[int]EventHandler)
+	c.entitiesSubID = 0
 	c.connected = true
 	c.mu.Unlock()
 
@@ -182,51 +240,233 @@ func (c *WSClient) Connect(ctx context.Context) error {
 	return nil
 }
 
-// SubscribeStateChanges subscribes to state change events
-func (c *WSClient) SubscribeStateChanges(ctx context.Context) error {
+// Call sends a command and waits for its result. The "id" field is assigned
+// automatically. Returns the result payload or the error reported by HA.
+// Requires Listen to be running.
+func (c *WSClient) Call(ctx context.Context, msg map[string]interface{}) (json.RawMessage, error) {
+	id, replyCh, err := c.send(msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removePending(id)
+
+	reply, err := c.waitReply(ctx, replyCh)
+	if err != nil {
+		return nil, fmt.Errorf("command %v: %w", msg["type"], err)
+	}
+
+	return reply.Result, nil
+}
+
+// Subscribe sends a subscription command and routes its events to handler
+// until Unsubscribe is called or the connection drops. Returns the subscription ID.
+func (c *WSClient) Subscribe(ctx context.Context, msg map[string]interface{}, handler EventHandler) (int, error) {
+	id, replyCh, err := c.send(msg, handler)
+	if err != nil {
+		return 0, err
+	}
+	defer c.removePending(id)
+
+	if _, err := c.waitReply(ctx, replyCh); err != nil {
+		c.mu.Lock()
+		delete(c.subscriptions, id)
+		c.mu.Unlock()
+		return 0, fmt.Errorf("subscription %v: %w", msg["type"], err)
+	}
+
+	return id, nil
+}
+
+// Unsubscribe cancels a subscription created by Subscribe
+func (c *WSClient) Unsubscribe(ctx context.Context, subscriptionID int) error {
+	c.mu.Lock()
+	delete(c.subscriptions, subscriptionID)
+	c.mu.Unlock()
+
+	_, err := c.Call(ctx, map[string]interface{}{
+		"type":         MsgTypeUnsubscribe,
+		"subscription": subscriptionID,
+	})
+	return err
+}
+
+// send assigns an ID, registers the pending reply (and subscription handler) and writes msg
+func (c *WSClient) send(msg map[string]interface{}, handler EventHandler) (int, chan *WSMessage, error) {
 	c.mu.Lock()
-	c.msgID++
-	msgID := c.msgID
 	conn := c.conn
+	if conn == nil || !c.connected {
+		c.mu.Unlock()
+		return 0, nil, ErrNotConnected
+	}
+	c.msgID++
+	id := c.msgID
+	replyCh := make(chan *WSMessage, 1)
+	c.pending[id] = replyCh
+	if handler != nil {
+		c.subscriptions[id] = handler
+	}
 	c.mu.Unlock()
 
-	if conn == nil {
-		return fmt.Errorf("not connected")
+	out := make(map[string]interface{}, len(msg)+1)
+	for k, v := range msg {
+		out[k] = v
+	}
+	out["id"] = id
+
+	c.writeMu.Lock()
+	err := conn.WriteJSON(out)
+	c.writeMu.Unlock()
+
+	if err != nil {
+		c.removePending(id)
+		c.mu.Lock()
+		delete(c.subscriptions, id)
+		c.mu.Unlock()
+		return 0, nil, fmt.Errorf("failed to send %v: %w", msg["type"], err)
+	}
+
+	return id, replyCh, nil
+}
+
+// waitReply waits for a reply, honouring ctx and the per-command timeout
+func (c *WSClient) waitReply(ctx context.Context, replyCh chan *WSMessage) (*WSMessage, error) {
+	timer := time.NewTimer(c.commandTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("timed out after %v", c.commandTimeout)
+	case reply, ok := <-replyCh:
+		if !ok || reply == nil {
+			return nil, errConnectionClosed
+		}
+		if !reply.Success {
+			if reply.Error != nil {
+				return nil, reply.Error
+			}
+			return nil, fmt.Errorf("unknown error")
+		}
+		return reply, nil
 	}
+}
+
+// removePending forgets a pending command
+func (c *WSClient) removePending(id int) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
 
-	msg := WSMessage{
-		ID:        msgID,
-		Type:      MsgTypeSubscribe,
-		EventType: EventTypeStateChange,
+// failPending closes all pending reply channels and drops subscriptions
+func (c *WSClient) failPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
 	}
+	c.subscriptions = make(map[int]EventHandler)
+	c.entitiesSubID = 0
+}
 
-	if err := conn.WriteJSON(msg); err != nil {
-		return fmt.Errorf("failed to subscribe: %w", err)
+// GetStates gets all entity states over the WebSocket connection
+func (c *WSClient) GetStates(ctx context.Context) ([]Entity, error) {
+	result, err := c.Call(ctx, map[string]interface{}{"type": MsgTypeGetStates})
+	if err != nil {
+		return nil, err
 	}
 
-	// Read subscription result
-	var result WSMessage
-	if err := conn.ReadJSON(&result); err != nil {
-		return fmt.Errorf("failed to read subscription result: %w", err)
+	var entities []Entity
+	if err := json.Unmarshal(result, &entities); err != nil {
+		return nil, fmt.Errorf("failed to decode states: %w", err)
 	}
 
-	if result.Type == MsgTypeResult && !result.Success {
-		errMsg := "unknown error"
-		if result.Error != nil {
-			errMsg = result.Error.Message
-		}
-		return fmt.Errorf("subscription failed: %s", errMsg)
+	return entities, nil
+}
+
+// CallService calls a service over the WebSocket connection
+func (c *WSClient) CallService(ctx context.Context, domain, service string, target ServiceTarget, data map[string]interface{}) error {
+	msg := map[string]interface{}{
+		"type":    MsgTypeCallService,
+		"domain":  domain,
+		"service": service,
+	}
+	if len(data) > 0 {
+		msg["service_data"] = data
+	}
+	if !target.IsEmpty() {
+		msg["target"] = target
 	}
 
-	logger.Info("Subscribed to state_changed events")
-	return nil
+	_, err := c.Call(ctx, msg)
+	return err
 }
 
-// OnStateChange registers a handler for specific entity state changes
+// RenderTemplate renders a template over the WebSocket connection.
+// HA streams template results as events; only the first one is used.
+func (c *WSClient) RenderTemplate(ctx context.Context, template string, variables map[string]interface{}) (string, error) {
+	results := make(chan json.RawMessage, 1)
+
+	msg := map[string]interface{}{
+		"type":     MsgTypeRenderTpl,
+		"template": template,
+	}
+	if len(variables) > 0 {
+		msg["variables"] = variables
+	}
+
+	id, err := c.Subscribe(ctx, msg, func(raw json.RawMessage) {
+		select {
+		case results <- raw:
+		default:
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := c.Unsubscribe(context.Background(), id); err != nil {
+			logger.Debug("Failed to unsubscribe from template %d: %v", id, err)
+		}
+	}()
+
+	timer := time.NewTimer(c.commandTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-timer.C:
+		return "", fmt.Errorf("template result timed out after %v", c.commandTimeout)
+	case raw := <-results:
+		var rendered struct {
+			Result interface{} `json:"result"`
+			Error  string      `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &rendered); err != nil {
+			return "", fmt.Errorf("failed to decode template result: %w", err)
+		}
+		if rendered.Error != "" {
+			return "", fmt.Errorf("template error: %s", rendered.Error)
+		}
+		if str, ok := rendered.Result.(string); ok {
+			return str, nil
+		}
+		return fmt.Sprint(rendered.Result), nil
+	}
+}
+
+// OnStateChange registers a handler for specific entity state changes.
+// If already subscribed, the subscription is extended to the new entity.
 func (c *WSClient) OnStateChange(entityID string, handler StateChangeHandler) {
 	c.handlersMu.Lock()
-	defer c.handlersMu.Unlock()
 	c.handlers[entityID] = append(c.handlers[entityID], handler)
+	c.handlersMu.Unlock()
+
+	go c.refreshSubscription(context.Background())
 }
 
 // OnAnyStateChange registers a handler for all state changes
@@ -234,8 +474,29 @@ func (c *WSClient) OnAnyStateChange(handler StateChangeHandler) {
 	c.OnStateChange("*", handler)
 }
 
-// Listen starts listening for events
+// Listen reads messages until the connection fails, dispatching command
+// results and subscription events. It is the only reader of the connection.
+// While listening, the connection is probed with pings; a missing pong or
+// silence longer than the read deadline is treated as a dead connection.
 func (c *WSClient) Listen(ctx context.Context) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	// Whatever ends the loop, nobody will answer pending commands anymore
+	defer c.failPending()
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.keepalive(ctx, conn, done)
+
+	// Any message (events, results, pongs) proves the connection is alive
+	readTimeout := c.pingInterval + c.pongTimeout
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -243,97 +504,128 @@ func (c *WSClient) Listen(ctx context.Context) error {
 		case <-c.stopChan:
 			return nil
 		default:
-			c.mu.Lock()
-			conn := c.conn
-			c.mu.Unlock()
-
-			if conn == nil {
-				return fmt.Errorf("not connected")
-			}
+		}
 
-			var msg WSMessage
-			if err := conn.ReadJSON(&msg); err != nil {
-				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-					return nil
-				}
-				return fmt.Errorf("read error: %w", err)
-			}
+		if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			return fmt.Errorf("failed to set read deadline: %w", err)
+		}
 
-			if msg.Type == MsgTypeEvent && msg.Event != nil {
-				c.handleEvent(msg.Event)
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
 			}
+			return fmt.Errorf("read error: %w", err)
 		}
-	}
-}
 
-// handleEvent processes incoming events
-func (c *WSClient) handleEvent(event *WSEvent) {
-	if event.EventType != EventTypeStateChange || event.Data == nil {
-		return
+		c.dispatch(&msg)
 	}
+}
 
-	entityID := event.Data.EntityID
-	oldState := event.Data.OldState
-	newState := event.Data.NewState
-
-	c.handlersMu.RLock()
-	defer c.handlersMu.RUnlock()
+// keepalive sends HA-level pings until done is closed. If a pong doesn't
+// arrive in time, the connection is closed so Listen fails and reconnects.
+// Cancelling ctx also closes the connection to unblock the pending read.
+func (c *WSClient) keepalive(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
 
-	// Call specific entity handlers
-	if handlers, ok := c.handlers[entityID]; ok {
-		for _, h := range handlers {
-			go h(entityID, oldState, newState)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			conn.Close()
+			return
+		case <-ticker.C:
+			if _, err := c.Ping(ctx); err != nil {
+				logger.Warn("WebSocket ping failed: %v, closing connection", err)
+				conn.Close()
+				return
+			}
 		}
 	}
+}
 
-	// Call wildcard handlers
-	if handlers, ok := c.handlers["*"]; ok {
-		for _, h := range handlers {
-			go h(entityID, oldState, newState)
-		}
+// Ping sends a ping command and returns the round-trip latency
+func (c *WSClient) Ping(ctx context.Context) (time.Duration, error) {
+	pingCtx, cancel := context.WithTimeout(ctx, c.pongTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Call(pingCtx, map[string]interface{}{"type": MsgTypePing}); err != nil {
+		return 0, err
 	}
+	latency := time.Since(start)
+
+	c.mu.Lock()
+	c.lastPong = time.Now()
+	c.pongLatency = latency
+	c.mu.Unlock()
+
+	logger.Debug("WebSocket pong received in %v", latency)
+	return latency, nil
 }
 
-// RunWithReconnect runs the client with automatic reconnection
-func (c *WSClient) RunWithReconnect(ctx context.Context) error {
-	delay := c.reconnectDelay
+// LastPong returns the latency of the last successful ping and when it was received.
+// Zero time means no pong was received yet.
+func (c *WSClient) LastPong() (latency time.Duration, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pongLatency, c.lastPong
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-c.stopChan:
-			return nil
-		default:
+// dispatch routes a received message to the pending command or subscription
+func (c *WSClient) dispatch(msg *WSMessage) {
+	switch msg.Type {
+	case MsgTypeResult, MsgTypePong:
+		// Pongs carry no success flag
+		if msg.Type == MsgTypePong {
+			msg.Success = true
 		}
 
-		// Connect
-		if err := c.Connect(ctx); err != nil {
-			logger.Error("WebSocket connection failed: %v, retrying in %v", err, delay)
-			time.Sleep(delay)
-			delay = min(delay*2, c.maxReconnect)
-			continue
+		c.mu.Lock()
+		replyCh, ok := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+
+		if ok {
+			replyCh <- msg
+		} else {
+			logger.Debug("Dropping result for unknown command %d", msg.ID)
 		}
 
-		// Reset delay on successful connection
-		delay = c.reconnectDelay
+	case MsgTypeEvent:
+		c.mu.Lock()
+		handler := c.subscriptions[msg.ID]
+		c.mu.Unlock()
 
-		// Subscribe to events
-		if err := c.SubscribeStateChanges(ctx); err != nil {
-			logger.Error("Failed to subscribe: %v", err)
-			c.Close()
-			continue
+		if handler != nil {
+			handler(msg.Event)
 		}
+	}
+}
 
-		// Listen for events
-		if err := c.Listen(ctx); err != nil {
-			logger.Error("WebSocket listen error: %v, reconnecting...", err)
-			c.Close()
-			continue
-		}
+// dispatchStateChange queues the change for handlers registered for the entity
+// and for wildcard handlers; each queue is processed in order
+func (c *WSClient) dispatchStateChange(entityID string, oldState, newState *Entity) {
+	c.handlersMu.RLock()
+	entityHandlers := c.handlers[entityID]
+	wildcardHandlers := c.handlers["*"]
+	c.handlersMu.RUnlock()
+
+	if len(entityHandlers) > 0 {
+		c.dispatcher.enqueue(entityID, stateChange{entityID, oldState, newState, entityHandlers})
+	}
+	if len(wildcardHandlers) > 0 {
+		c.dispatcher.enqueue("*", stateChange{entityID, oldState, newState, wildcardHandlers})
 	}
 }
 
+// DispatchStats returns state change delivery counters
+func (c *WSClient) DispatchStats() DispatchStats {
+	return c.dispatcher.stats()
+}
+
 // Close closes the WebSocket connection
 func (c *WSClient) Close() error {
 	c.mu.Lock()
@@ -352,6 +644,7 @@ func (c *WSClient) Close() error {
 func (c *WSClient) Stop() {
 	close(c.stopChan)
 	c.Close()
+	c.dispatcher.stop()
 }
 
 // IsConnected returns connection status
@@ -0,0 +1,461 @@
+package homeassistant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// mockHA is a minimal Home Assistant WebSocket server for tests.
+// handle is called for every command after authentication; it may write
+// any number of messages via send.
+type mockHA struct {
+	t      *testing.T
+	server *httptest.Server
+	handle func(msg map[string]interface{}, send func(v interface{}))
+}
+
+func newMockHA(t *testing.T, handle func(msg map[string]interface{}, send func(v interface{}))) *mockHA {
+	m := &mockHA{t: t, handle: handle}
+	upgrader := websocket.Upgrader{}
+
+	m.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var writeMu sync.Mutex
+		send := func(v interface{}) {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := conn.WriteJSON(v); err != nil {
+				t.Logf("mock write failed: %v", err)
+			}
+		}
+
+		send(map[string]interface{}{"type": MsgTypeAuthRequired})
+
+		var auth map[string]interface{}
+		if err := conn.ReadJSON(&auth); err != nil {
+			return
+		}
+		if auth["access_token"] != "test_token" {
+			send(map[string]interface{}{"type": MsgTypeAuthInvalid})
+			return
+		}
+		send(map[string]interface{}{"type": MsgTypeAuthOK})
+
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			m.handle(msg, send)
+		}
+	}))
+
+	return m
+}
+
+func (m *mockHA) close() {
+	m.server.Close()
+}
+
+// connectTestClient connects a client to the mock and starts its reader
+func connectTestClient(t *testing.T, m *mockHA) (*WSClient, context.CancelFunc) {
+	client := NewWSClient(m.server.URL+"/api", "test_token")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := client.Connect(ctx); err != nil {
+		cancel()
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	go func() {
+		_ = client.Listen(ctx)
+	}()
+
+	return client, func() {
+		cancel()
+		client.Close()
+	}
+}
+
+func result(id interface{}, success bool, payload interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"type":    MsgTypeResult,
+		"success": success,
+		"result":  payload,
+	}
+}
+
+func TestGetWSURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"http://supervisor/core/api", "ws://supervisor/core/websocket"},
+		{"http://192.168.1.100:8123/api", "ws://192.168.1.100:8123/api/websocket"},
+		{"https://ha.example.com/api/", "wss://ha.example.com/api/websocket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			client := NewWSClient(tt.baseURL, "token")
+			if got := client.getWSURL(); got != tt.want {
+				t.Errorf("getWSURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWSConnectInvalidToken(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {})
+	defer m.close()
+
+	client := NewWSClient(m.server.URL+"/api", "wrong_token")
+	if err := client.Connect(context.Background()); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Connect() error = %v, want ErrAuthFailed", err)
+	}
+	if _, err := client.Call(context.Background(), map[string]interface{}{"type": MsgTypeGetStates}); err != ErrNotConnected {
+		t.Errorf("Call() after failed auth error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestWSCallDuringHandshake(t *testing.T) {
+	authRequired := make(chan struct{})
+	release := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteJSON(map[string]interface{}{"type": MsgTypeAuthRequired})
+		var auth map[string]interface{}
+		if err := conn.ReadJSON(&auth); err != nil {
+			return
+		}
+		close(authRequired)
+		<-release
+		conn.WriteJSON(map[string]interface{}{"type": MsgTypeAuthOK})
+
+		// Nothing but the auth message may arrive before auth_ok
+		var msg map[string]interface{}
+		conn.ReadJSON(&msg)
+	}))
+	defer server.Close()
+
+	client := NewWSClient(server.URL+"/api", "test_token")
+	connectErr := make(chan error, 1)
+	go func() {
+		connectErr <- client.Connect(context.Background())
+	}()
+
+	<-authRequired
+	_, err := client.Call(context.Background(), map[string]interface{}{"type": MsgTypeGetStates})
+	if err != ErrNotConnected {
+		t.Errorf("Call() during handshake error = %v, want ErrNotConnected", err)
+	}
+	close(release)
+
+	if err := <-connectErr; err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	client.Close()
+}
+
+func TestWSCallInterleavedWithEvents(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		switch msg["type"] {
+		case MsgTypeSubscribeEntities:
+			send(result(msg["id"], true, nil))
+		case MsgTypeGetStates:
+			// An unrelated event arrives before the reply
+			send(map[string]interface{}{
+				"id":   1,
+				"type": MsgTypeEvent,
+				"event": map[string]interface{}{
+					"a": map[string]interface{}{
+						"light.kitchen": map[string]interface{}{"s": "on", "a": map[string]interface{}{}, "lc": 1767520800.0},
+					},
+				},
+			})
+			send(result(msg["id"], true, []Entity{{EntityID: "light.kitchen", State: "on"}}))
+		case MsgTypeCallService:
+			send(map[string]interface{}{
+				"id":      msg["id"],
+				"type":    MsgTypeResult,
+				"success": false,
+				"error":   map[string]string{"code": "not_found", "message": "Service not found"},
+			})
+		}
+	})
+	defer m.close()
+
+	client, stop := connectTestClient(t, m)
+	defer stop()
+
+	events := make(chan string, 1)
+	client.OnStateChange("light.kitchen", func(entityID string, oldState, newState *Entity) {
+		events <- newState.State
+	})
+
+	ctx := context.Background()
+	if err := client.SubscribeStateChanges(ctx); err != nil {
+		t.Fatalf("SubscribeStateChanges() error = %v", err)
+	}
+
+	states, err := client.GetStates(ctx)
+	if err != nil {
+		t.Fatalf("GetStates() error = %v", err)
+	}
+	if len(states) != 1 || states[0].EntityID != "light.kitchen" {
+		t.Errorf("GetStates() = %v, want [light.kitchen]", states)
+	}
+
+	select {
+	case state := <-events:
+		if state != "on" {
+			t.Errorf("event state = %v, want on", state)
+		}
+	case <-time.After(time.Second):
+		t.Error("state change event was not dispatched")
+	}
+
+	err = client.CallService(ctx, "light", "nonexistent", EntityTarget("light.kitchen"), nil)
+	if err == nil {
+		t.Fatal("CallService() expected error")
+	}
+	var wsErr *WSError
+	if !errors.As(err, &wsErr) || wsErr.Code != "not_found" {
+		t.Errorf("CallService() error = %v, want WSError not_found", err)
+	}
+}
+
+func TestWSCallTimeout(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		// Never reply
+	})
+	defer m.close()
+
+	client, stop := connectTestClient(t, m)
+	defer stop()
+	client.commandTimeout = 50 * time.Millisecond
+
+	if _, err := client.Call(context.Background(), map[string]interface{}{"type": MsgTypeGetStates}); err == nil {
+		t.Error("Call() expected timeout error")
+	}
+
+	client.mu.Lock()
+	pending := len(client.pending)
+	client.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("pending commands = %d, want 0 after timeout", pending)
+	}
+}
+
+func TestWSRenderTemplate(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		switch msg["type"] {
+		case MsgTypeRenderTpl:
+			send(result(msg["id"], true, nil))
+			send(map[string]interface{}{
+				"id":    msg["id"],
+				"type":  MsgTypeEvent,
+				"event": map[string]interface{}{"result": "21.5"},
+			})
+		case MsgTypeUnsubscribe:
+			send(result(msg["id"], true, nil))
+		}
+	})
+	defer m.close()
+
+	client, stop := connectTestClient(t, m)
+	defer stop()
+
+	got, err := client.RenderTemplate(context.Background(), "{{ states('sensor.temperature') }}", nil)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if got != "21.5" {
+		t.Errorf("RenderTemplate() = %q, want 21.5", got)
+	}
+}
+
+func TestWSCallNotConnected(t *testing.T) {
+	client := NewWSClient("http://localhost:8123/api", "test_token")
+
+	_, err := client.Call(context.Background(), map[string]interface{}{"type": MsgTypeGetStates})
+	if err != ErrNotConnected {
+		t.Errorf("Call() error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestWSSubscribeEntitiesDiffs(t *testing.T) {
+	subscribed := make(chan []interface{}, 2)
+
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		switch msg["type"] {
+		case MsgTypeSubscribeEntities:
+			ids, _ := msg["entity_ids"].([]interface{})
+			subscribed <- ids
+			send(result(msg["id"], true, nil))
+			if len(ids) != 1 {
+				return
+			}
+			send(map[string]interface{}{
+				"id":   msg["id"],
+				"type": MsgTypeEvent,
+				"event": map[string]interface{}{
+					"a": map[string]interface{}{
+						"binary_sensor.power": map[string]interface{}{
+							"s":  "on",
+							"a":  map[string]interface{}{"friendly_name": "Power", "icon": "mdi:flash"},
+							"lc": 1767520800.5,
+						},
+					},
+				},
+			})
+			send(map[string]interface{}{
+				"id":   msg["id"],
+				"type": MsgTypeEvent,
+				"event": map[string]interface{}{
+					"c": map[string]interface{}{
+						"binary_sensor.power": map[string]interface{}{
+							"+": map[string]interface{}{"s": "off", "lc": 1767524400.0},
+							"-": map[string]interface{}{"a": []string{"icon"}},
+						},
+					},
+				},
+			})
+		case MsgTypeUnsubscribe:
+			send(result(msg["id"], true, nil))
+		}
+	})
+	defer m.close()
+
+	client, stop := connectTestClient(t, m)
+	defer stop()
+
+	changes := make(chan [2]*Entity, 4)
+	client.OnStateChange("binary_sensor.power", func(entityID string, oldState, newState *Entity) {
+		changes <- [2]*Entity{oldState, newState}
+	})
+
+	if err := client.SubscribeStateChanges(context.Background()); err != nil {
+		t.Fatalf("SubscribeStateChanges() error = %v", err)
+	}
+
+	if ids := <-subscribed; len(ids) != 1 || ids[0] != "binary_sensor.power" {
+		t.Fatalf("subscribed entity_ids = %v, want [binary_sensor.power]", ids)
+	}
+
+	// Initial snapshot and the diff are both reported
+	for i := 0; i < 2; i++ {
+		select {
+		case <-changes:
+		case <-time.After(time.Second):
+			t.Fatal("state change was not dispatched")
+		}
+	}
+
+	client.mu.Lock()
+	power := client.entityStates["binary_sensor.power"]
+	client.mu.Unlock()
+
+	if power.State != "off" {
+		t.Errorf("State = %v, want off", power.State)
+	}
+	if _, ok := power.Attributes["icon"]; ok {
+		t.Error("icon attribute should be removed by diff")
+	}
+	if power.Attributes["friendly_name"] != "Power" {
+		t.Errorf("friendly_name = %v, want Power", power.Attributes["friendly_name"])
+	}
+	if power.LastChanged != "2026-01-04T11:00:00Z" {
+		t.Errorf("LastChanged = %v, want 2026-01-04T11:00:00Z", power.LastChanged)
+	}
+
+	// Registering another entity resubscribes with the extended list
+	client.OnStateChange("sensor.next_power_on", func(entityID string, oldState, newState *Entity) {})
+
+	select {
+	case ids := <-subscribed:
+		if len(ids) != 2 {
+			t.Errorf("resubscribed entity_ids = %v, want 2 entities", ids)
+		}
+	case <-time.After(time.Second):
+		t.Error("client did not resubscribe after handler change")
+	}
+}
+
+func TestWSPing(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		if msg["type"] == MsgTypePing {
+			send(map[string]interface{}{"id": msg["id"], "type": MsgTypePong})
+		}
+	})
+	defer m.close()
+
+	client, stop := connectTestClient(t, m)
+	defer stop()
+
+	if _, at := client.LastPong(); !at.IsZero() {
+		t.Error("LastPong() should be zero before the first ping")
+	}
+
+	if _, err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if _, at := client.LastPong(); at.IsZero() {
+		t.Error("LastPong() should be set after a successful ping")
+	}
+}
+
+func TestWSKeepaliveDetectsDeadConnection(t *testing.T) {
+	m := newMockHA(t, func(msg map[string]interface{}, send func(v interface{})) {
+		// Half-open connection: nothing is ever answered
+	})
+	defer m.close()
+
+	client := NewWSClient(m.server.URL+"/api", "test_token")
+	client.pingInterval = 50 * time.Millisecond
+	client.pongTimeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- client.Listen(ctx)
+	}()
+
+	select {
+	case err := <-listenErr:
+		if err == nil {
+			t.Error("Listen() should fail on a dead connection")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Listen() did not detect the dead connection")
+	}
+}
@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// Connection alerts go to bot admins (allowed_chat_ids), not to the
+// notification channels: they are about the add-on, not about power.
+
+// NotifyConnectionLost tells admins that Home Assistant has been unreachable for downFor
+func (s *Service) NotifyConnectionLost(downFor time.Duration) error {
+	text := fmt.Sprintf("%s *Немає зв'язку з Home Assistant* вже %s\n\nСповіщення про світло можуть не надходити.",
+		IconWarning, formatDuration(downFor))
+	return s.sendToAdmins(text)
+}
+
+// NotifyConnectionRestored tells admins that the connection is back after downFor
+func (s *Service) NotifyConnectionRestored(downFor time.Duration) error {
+	text := fmt.Sprintf("%s *Зв'язок з Home Assistant відновлено*\n%s Зв'язку не було *%s*",
+		IconOK, IconTime, formatDuration(downFor))
+	return s.sendToAdmins(text)
+}
+
+// NotifyAuthFailed tells admins that Home Assistant rejected the access token
+func (s *Service) NotifyAuthFailed() error {
+	text := fmt.Sprintf("%s *Home Assistant відхилив токен доступу*\n\nПеревірте налаштування доповнення.", IconWarning)
+	return s.sendToAdmins(text)
+}
+
+// sendToAdmins sends message to bot admin chats
+func (s *Service) sendToAdmins(text string) error {
+	admins := s.config.AdminChatIDs()
+	if len(admins) == 0 {
+		logger.Debug("No admin chats configured, skipping connection alert")
+		return nil
+	}
+	return s.sendToChats(admins, text)
+}
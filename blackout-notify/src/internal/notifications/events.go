@@ -0,0 +1,81 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// Home Assistant event types fired by the add-on
+const (
+	EventPowerChanged    = "blackout_notify_power_changed"
+	EventScheduleChanged = "blackout_notify_schedule_changed"
+)
+
+// PowerEvent holds the data shared by Telegram notifications and Home Assistant events
+type PowerEvent struct {
+	State             string        // Current power state: "on" or "off"
+	PreviousState     string        // Power state before the transition (empty for schedule changes)
+	Time              time.Time     // When the event happened
+	OutageDuration    time.Duration // Length of the outage that just ended (power on only)
+	ScheduleType      string        // What NextScheduled refers to: "on" or "off"
+	NextScheduled     *time.Time    // Next scheduled change, nil if unknown
+	PreviousScheduled *time.Time    // Previous scheduled time (schedule changes only)
+	Paused            bool          // Telegram notifications are paused
+	Inferred          bool          // Transition derived from connectivity to HA rather than a sensor
+}
+
+// EventData converts the event to Home Assistant event data
+func (e PowerEvent) EventData() map[string]interface{} {
+	data := map[string]interface{}{
+		"state":         e.State,
+		"time":          e.Time.Format(time.RFC3339),
+		"schedule_type": e.ScheduleType,
+		"paused":        e.Paused,
+		"inferred":      e.Inferred,
+	}
+
+	if e.PreviousState != "" {
+		data["previous_state"] = e.PreviousState
+	}
+	if e.OutageDuration > 0 {
+		data["outage_duration_minutes"] = int(e.OutageDuration.Minutes())
+	}
+	if e.NextScheduled != nil {
+		data["next_scheduled"] = e.NextScheduled.Format(time.RFC3339)
+		data["minutes_until_scheduled"] = int(e.NextScheduled.Sub(e.Time).Minutes())
+	}
+	if e.PreviousScheduled != nil {
+		data["previous_scheduled"] = e.PreviousScheduled.Format(time.RFC3339)
+	}
+
+	return data
+}
+
+// FireScheduleChanged fires a schedule change event in Home Assistant.
+// Unlike NotifyScheduleChanged it is sent for every change, regardless of power state.
+func (s *Service) FireScheduleChanged(ctx context.Context, state, scheduleType string, oldTime, newTime *time.Time) {
+	s.fireEvent(ctx, EventScheduleChanged, PowerEvent{
+		State:             state,
+		Time:              time.Now().In(s.location),
+		ScheduleType:      scheduleType,
+		NextScheduled:     newTime,
+		PreviousScheduled: oldTime,
+		Paused:            s.isPaused(ctx),
+	})
+}
+
+// fireEvent fires a Home Assistant event if events are enabled
+func (s *Service) fireEvent(ctx context.Context, eventType string, event PowerEvent) {
+	if !s.config.FireEvents {
+		return
+	}
+
+	if err := s.haClient.FireEvent(ctx, eventType, event.EventData()); err != nil {
+		logger.Warn("Failed to fire %s event: %v", eventType, err)
+		return
+	}
+
+	logger.Debug("Fired %s event (state=%s)", eventType, event.State)
+}
@@ -0,0 +1,23 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+)
+
+// Heartbeat receiver notifications. The receiver runs without Home Assistant,
+// so unlike NotifyPowerOn/Off nothing is looked up, fired or rendered there.
+
+// NotifyHeartbeatLost announces an outage detected by missing heartbeats
+func (s *Service) NotifyHeartbeatLost(lastSeen time.Time) error {
+	text := fmt.Sprintf("%s *Світло вимкнено*\n_(сигнал з дому не надходить з %s)_",
+		IconPowerOff, lastSeen.In(s.location).Format("15:04"))
+	return s.sendToAllChats(text)
+}
+
+// NotifyHeartbeatRestored announces restoration after heartbeats resumed
+func (s *Service) NotifyHeartbeatRestored(outageDuration time.Duration) error {
+	text := fmt.Sprintf("%s *Світло повернулось!*\n_(сигнал з дому відновився)_\n%s Світла не було *%s*",
+		IconPowerOn, IconTime, formatDuration(outageDuration))
+	return s.sendToAllChats(text)
+}
@@ -0,0 +1,270 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// IconResume marks notifications being resumed
+const IconResume = "▶️"
+
+// CallbackPause prefixes callback data of the pause button under notifications;
+// the payload is a duration, e.g. "pause:1h"
+const CallbackPause = "pause"
+
+// pauseFile stores a pause started from Telegram so its expiry survives restarts
+const pauseFile = "pause.json"
+
+// pauseState is a pause started from Telegram
+type pauseState struct {
+	Internal bool       `json:"internal"`        // Paused by the internal flag, not the pause entity
+	Until    *time.Time `json:"until,omitempty"` // Automatic resume time; nil pauses indefinitely
+}
+
+// PauseStatus describes whether notifications are paused
+type PauseStatus struct {
+	Paused bool
+	Until  *time.Time // Set when the pause expires automatically
+}
+
+// Pause pauses power notifications, for d if positive or until resumed otherwise.
+// The pause entity is turned on if it exists in Home Assistant, otherwise an
+// internal flag is set. by names who paused, for the note in notification chats.
+func (s *Service) Pause(ctx context.Context, d time.Duration, by string) (PauseStatus, error) {
+	state := pauseState{Internal: !s.pauseEntityExists(ctx)}
+	if d > 0 {
+		until := time.Now().Add(d)
+		state.Until = &until
+	}
+
+	if !state.Internal {
+		if err := s.haClient.TurnOn(ctx, s.config.PauseEntityID); err != nil {
+			return PauseStatus{}, fmt.Errorf("failed to turn on %s: %w", s.config.PauseEntityID, err)
+		}
+	}
+
+	s.pauseMu.Lock()
+	s.pause = &state
+	s.schedulePauseExpiryLocked()
+	s.pauseMu.Unlock()
+	s.savePause(&state)
+
+	logger.Info("Notifications paused by %s until %s", by, formatPauseUntil(state.Until, s.location))
+
+	text := fmt.Sprintf("%s *Сповіщення призупинено*", IconPause)
+	if state.Until != nil {
+		text += fmt.Sprintf(" до %s", state.Until.In(s.location).Format("15:04"))
+	}
+	text += formatPausedBy(by)
+	if err := s.sendToAllChats(text); err != nil {
+		logger.Warn("Failed to announce pause: %v", err)
+	}
+
+	return PauseStatus{Paused: true, Until: state.Until}, nil
+}
+
+// Resume resumes power notifications paused from Telegram or by the pause entity.
+// by names who resumed; empty when the pause expired.
+func (s *Service) Resume(ctx context.Context, by string) error {
+	s.pauseMu.Lock()
+	state := s.pause
+	s.pause = nil
+	if s.pauseTimer != nil {
+		s.pauseTimer.Stop()
+		s.pauseTimer = nil
+	}
+	s.pauseMu.Unlock()
+	s.savePause(nil)
+
+	// The entity may also have been turned on in Home Assistant directly
+	if (state == nil || !state.Internal) && s.pauseEntityExists(ctx) {
+		if err := s.haClient.TurnOff(ctx, s.config.PauseEntityID); err != nil {
+			return fmt.Errorf("failed to turn off %s: %w", s.config.PauseEntityID, err)
+		}
+	}
+
+	if by != "" {
+		logger.Info("Notifications resumed by %s", by)
+	} else {
+		logger.Info("Notification pause expired")
+	}
+
+	text := fmt.Sprintf("%s *Сповіщення відновлено*", IconResume)
+	text += formatPausedBy(by)
+	if err := s.sendToAllChats(text); err != nil {
+		logger.Warn("Failed to announce resume: %v", err)
+	}
+
+	return nil
+}
+
+// PauseStatus reports whether notifications are paused and until when
+func (s *Service) PauseStatus(ctx context.Context) PauseStatus {
+	s.pauseMu.Lock()
+	state := s.pause
+	s.pauseMu.Unlock()
+
+	// The pause entity may have been turned off in Home Assistant meanwhile
+	status := PauseStatus{Paused: s.isPaused(ctx)}
+	if status.Paused && state != nil {
+		status.Until = state.Until
+	}
+	return status
+}
+
+// RestorePause resumes a pause saved before a restart, or ends it if it expired meanwhile
+func (s *Service) RestorePause(ctx context.Context) {
+	data, err := os.ReadFile(filepath.Join(s.config.DataDir, pauseFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		logger.Warn("Failed to read pause state: %v", err)
+		return
+	}
+
+	var state pauseState
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.Warn("Failed to decode pause state: %v", err)
+		return
+	}
+
+	s.pauseMu.Lock()
+	s.pause = &state
+	expired := state.Until != nil && !state.Until.After(time.Now())
+	if !expired {
+		s.schedulePauseExpiryLocked()
+	}
+	s.pauseMu.Unlock()
+
+	if expired {
+		if err := s.Resume(ctx, ""); err != nil {
+			logger.Warn("Failed to end expired pause: %v", err)
+		}
+		return
+	}
+
+	logger.Info("Notifications paused until %s", formatPauseUntil(state.Until, s.location))
+}
+
+// schedulePauseExpiryLocked (re)starts the automatic resume timer; caller must hold s.pauseMu
+func (s *Service) schedulePauseExpiryLocked() {
+	if s.pauseTimer != nil {
+		s.pauseTimer.Stop()
+		s.pauseTimer = nil
+	}
+	if s.pause == nil || s.pause.Until == nil {
+		return
+	}
+
+	until := *s.pause.Until
+	s.pauseTimer = time.AfterFunc(time.Until(until), func() {
+		s.pauseMu.Lock()
+		current := s.pause != nil && s.pause.Until != nil && s.pause.Until.Equal(until)
+		s.pauseMu.Unlock()
+		if !current {
+			return
+		}
+
+		ctx := context.Background()
+
+		// Already resumed in Home Assistant, nothing to announce
+		if !s.isPaused(ctx) {
+			s.pauseMu.Lock()
+			s.pause = nil
+			s.pauseMu.Unlock()
+			s.savePause(nil)
+			return
+		}
+
+		if err := s.Resume(ctx, ""); err != nil {
+			logger.Error("Failed to resume notifications: %v", err)
+		}
+	})
+}
+
+// pauseEntityExists checks whether the configured pause entity exists in Home Assistant
+func (s *Service) pauseEntityExists(ctx context.Context) bool {
+	if s.config.PauseEntityID == "" || s.states == nil {
+		return false
+	}
+
+	_, err := s.states.GetState(ctx, s.config.PauseEntityID)
+	if err != nil && !errors.Is(err, homeassistant.ErrNotFound) {
+		logger.Warn("Failed to check pause entity %s: %v", s.config.PauseEntityID, err)
+	}
+	return err == nil
+}
+
+// savePause persists the Telegram pause; nil removes it
+func (s *Service) savePause(state *pauseState) {
+	path := filepath.Join(s.config.DataDir, pauseFile)
+
+	if state == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to remove pause state: %v", err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		logger.Warn("Failed to encode pause state: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		logger.Warn("Failed to save pause state: %v", err)
+	}
+}
+
+// sendWithPauseButton sends a power notification with a "pause for 1 hour"
+// button. Chats without the operator role get the plain message; in groups
+// the role of the member pressing the button is checked on click.
+func (s *Service) sendWithPauseButton(text string) error {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(IconPause+" Пауза на 1 год", CallbackPause+":1h"),
+	))
+
+	var withButton, plain []int64
+	for _, chatID := range s.config.NotificationChatIDs {
+		if s.config.HasRole(chatID, config.RoleOperator) {
+			withButton = append(withButton, chatID)
+		} else {
+			plain = append(plain, chatID)
+		}
+	}
+
+	errButton := s.sendToChatsWithKeyboard(withButton, text, &keyboard)
+	if err := s.sendToChats(plain, text); err != nil {
+		return err
+	}
+	return errButton
+}
+
+// formatPausedBy returns the line naming who paused or resumed, empty if by is.
+// Legacy Markdown allows no escapes inside italics, and usernames often contain
+// "_", so the name is escaped plain text.
+func formatPausedBy(by string) string {
+	if by == "" {
+		return ""
+	}
+	return "\n" + tgbotapi.EscapeText(tgbotapi.ModeMarkdown, by)
+}
+
+// formatPauseUntil formats the pause expiry for logs
+func formatPauseUntil(until *time.Time, loc *time.Location) string {
+	if until == nil {
+		return "resumed"
+	}
+	return until.In(loc).Format(time.RFC3339)
+}
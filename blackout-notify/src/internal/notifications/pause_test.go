@@ -0,0 +1,21 @@
+package notifications
+
+import "testing"
+
+func TestFormatPausedBy(t *testing.T) {
+	tests := []struct {
+		by   string
+		want string
+	}{
+		{"", ""},
+		{"@power_watch_bot", "\n@power\\_watch\\_bot"},
+		{"Олена *Admin*", "\nОлена \\*Admin\\*"},
+		{"[x](y)`", "\n\\[x](y)\\`"},
+	}
+
+	for _, tt := range tests {
+		if got := formatPausedBy(tt.by); got != tt.want {
+			t.Errorf("formatPausedBy(%q) = %q, want %q", tt.by, got, tt.want)
+		}
+	}
+}
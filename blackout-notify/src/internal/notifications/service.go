@@ -2,8 +2,10 @@ package notifications
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -21,93 +23,185 @@ const (
 	IconWarning  = "⚠️"
 	IconPause    = "⏸️"
 	IconUpdate   = "🔄"
+	IconOK       = "✅"
 )
 
+// calendarLookahead is how far ahead scheduled outages are read from the calendar
+const calendarLookahead = 48 * time.Hour
+
 // Service handles power notifications
 type Service struct {
 	bot      *tgbotapi.BotAPI
 	config   *config.Config
 	haClient *homeassistant.Client
+	states   homeassistant.StateReader
 	location *time.Location
+
+	// Pause started from Telegram
+	pauseMu    sync.Mutex
+	pause      *pauseState
+	pauseTimer *time.Timer
 }
 
-// NewService creates a new notification service
-func NewService(bot *tgbotapi.BotAPI, cfg *config.Config, haClient *homeassistant.Client) (*Service, error) {
+// NewService creates a new notification service. Entity states are read
+// through states, falling back to haClient if it is nil.
+func NewService(bot *tgbotapi.BotAPI, cfg *config.Config, haClient *homeassistant.Client, states homeassistant.StateReader) (*Service, error) {
 	loc, err := time.LoadLocation(cfg.Timezone)
 	if err != nil {
 		logger.Warn("Failed to load timezone %s, using UTC: %v", cfg.Timezone, err)
 		loc = time.UTC
 	}
 
+	if states == nil && haClient != nil {
+		states = haClient
+	}
+
 	return &Service{
 		bot:      bot,
 		config:   cfg,
 		haClient: haClient,
+		states:   states,
 		location: loc,
 	}, nil
 }
 
-// NotifyPowerOn sends notification when power is restored
-func (s *Service) NotifyPowerOn(ctx context.Context) error {
-	if s.isPaused(ctx) {
+// NotifyPowerOn sends notification when power is restored.
+// outageDuration is the length of the outage that just ended (zero if unknown);
+// inferred marks transitions derived from restored connectivity to Home Assistant.
+func (s *Service) NotifyPowerOn(ctx context.Context, outageDuration time.Duration, inferred bool) error {
+	event := s.newPowerEvent(ctx, "on", "off")
+	event.OutageDuration = outageDuration
+	event.Inferred = inferred
+	s.fireEvent(ctx, EventPowerChanged, event)
+
+	if event.Paused {
 		logger.Debug("Notifications paused, skipping power on notification")
 		return nil
 	}
 
-	now := time.Now().In(s.location)
-
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("%s *Світло повернулось!*", IconPowerOn))
+	appendInferredMarker(&sb, event)
 
-	// Get next scheduled off time
-	if s.config.NextOffSensorID != "" {
-		nextOff, err := s.getScheduledTime(ctx, s.config.NextOffSensorID)
-		if err != nil {
-			logger.Warn("Failed to get next off time: %v", err)
-		} else if nextOff != nil {
-			duration := nextOff.Sub(now)
-			sb.WriteString(fmt.Sprintf("\n\n%s Відключення через *%s* (%s)\n_за даними Yasno_",
-				IconSchedule,
-				formatDuration(duration),
-				nextOff.In(s.location).Format("15:04")))
-		}
+	if event.OutageDuration > 0 {
+		sb.WriteString(fmt.Sprintf("\n%s Світла не було *%s*", IconTime, formatDuration(event.OutageDuration)))
 	}
 
-	return s.sendToAllChats(sb.String())
+	// Next scheduled off time
+	if event.NextScheduled != nil {
+		duration := event.NextScheduled.Sub(event.Time)
+		sb.WriteString(fmt.Sprintf("\n\n%s Відключення через *%s* (%s)\n_за даними Yasno_",
+			IconSchedule,
+			formatDuration(duration),
+			event.NextScheduled.In(s.location).Format("15:04")))
+	}
+
+	s.appendTemplate(ctx, &sb, s.config.PowerOnTemplate, event)
+
+	return s.sendWithPauseButton(sb.String())
 }
 
-// NotifyPowerOff sends notification when power is lost
-func (s *Service) NotifyPowerOff(ctx context.Context) error {
-	if s.isPaused(ctx) {
+// NotifyPowerOff sends notification when power is lost.
+// inferred marks outages derived from lost connectivity to Home Assistant;
+// HA is unreachable then, so nothing is looked up, fired or rendered there.
+func (s *Service) NotifyPowerOff(ctx context.Context, inferred bool) error {
+	var event PowerEvent
+	if inferred {
+		event = PowerEvent{
+			State:         "off",
+			PreviousState: "on",
+			Time:          time.Now().In(s.location),
+			ScheduleType:  "on",
+			Inferred:      true,
+		}
+	} else {
+		event = s.newPowerEvent(ctx, "off", "on")
+		s.fireEvent(ctx, EventPowerChanged, event)
+	}
+
+	if event.Paused {
 		logger.Debug("Notifications paused, skipping power off notification")
 		return nil
 	}
 
-	now := time.Now().In(s.location)
-
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("%s *Світло вимкнено*", IconPowerOff))
+	appendInferredMarker(&sb, event)
+
+	// Next scheduled on time
+	if event.NextScheduled != nil {
+		duration := event.NextScheduled.Sub(event.Time)
+		sb.WriteString(fmt.Sprintf("\n\n%s Заживлення через *%s* (%s)\n_за даними Yasno_",
+			IconSchedule,
+			formatDuration(duration),
+			event.NextScheduled.In(s.location).Format("15:04")))
+	}
 
-	// Get next scheduled on time
-	if s.config.NextOnSensorID != "" {
-		nextOn, err := s.getScheduledTime(ctx, s.config.NextOnSensorID)
-		if err != nil {
-			logger.Warn("Failed to get next on time: %v", err)
-		} else if nextOn != nil {
-			duration := nextOn.Sub(now)
-			sb.WriteString(fmt.Sprintf("\n\n%s Заживлення через *%s* (%s)\n_за даними Yasno_",
-				IconSchedule,
-				formatDuration(duration),
-				nextOn.In(s.location).Format("15:04")))
+	if !inferred {
+		s.appendTemplate(ctx, &sb, s.config.PowerOffTemplate, event)
+	}
+
+	return s.sendWithPauseButton(sb.String())
+}
+
+// appendInferredMarker notes that the transition was derived from connectivity, not a sensor
+func appendInferredMarker(sb *strings.Builder, event PowerEvent) {
+	if event.Inferred {
+		sb.WriteString("\n_(визначено за зв'язком з Home Assistant)_")
+	}
+}
+
+// appendTemplate renders a user template in Home Assistant and appends it to the message.
+// Event data is available to the template as variables (e.g. {{ outage_duration_minutes }}).
+func (s *Service) appendTemplate(ctx context.Context, sb *strings.Builder, template string, event PowerEvent) {
+	if strings.TrimSpace(template) == "" {
+		return
+	}
+
+	rendered, err := s.haClient.RenderTemplate(ctx, template, event.EventData())
+	if err != nil {
+		logger.Warn("Failed to render notification template: %v", err)
+		return
+	}
+
+	if rendered = strings.TrimSpace(rendered); rendered != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(rendered)
+	}
+}
+
+// newPowerEvent builds the payload for a power transition, looking up the
+// next scheduled change in the opposite direction
+func (s *Service) newPowerEvent(ctx context.Context, state, previousState string) PowerEvent {
+	event := PowerEvent{
+		State:         state,
+		PreviousState: previousState,
+		Time:          time.Now().In(s.location),
+		ScheduleType:  previousState,
+		Paused:        s.isPaused(ctx),
+	}
+
+	if s.HasSchedule(event.ScheduleType) {
+		// Transient failures are already retried by the client; what's left is reported here
+		next, err := s.GetNextScheduledTime(ctx, event.ScheduleType)
+		switch {
+		case errors.Is(err, homeassistant.ErrNotFound):
+			logger.Warn("Schedule source for next %s time not found, check configuration: %v", event.ScheduleType, err)
+		case errors.Is(err, homeassistant.ErrUnavailable):
+			logger.Warn("Home Assistant unavailable, sending without next %s time: %v", event.ScheduleType, err)
+		case err != nil:
+			logger.Warn("Failed to get next %s time: %v", event.ScheduleType, err)
+		default:
+			event.NextScheduled = next
 		}
 	}
 
-	return s.sendToAllChats(sb.String())
+	return event
 }
 
 // getScheduledTime retrieves and parses time from a sensor
 func (s *Service) getScheduledTime(ctx context.Context, sensorID string) (*time.Time, error) {
-	entity, err := s.haClient.GetState(ctx, sensorID)
+	entity, err := s.states.GetState(ctx, sensorID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sensor state: %w", err)
 	}
@@ -158,13 +252,48 @@ func (s *Service) getScheduledTime(ctx context.Context, sensorID string) (*time.
 	return &parsedTime, nil
 }
 
-// isPaused checks if notifications are paused via HA input_boolean
+// getCalendarSchedule derives next power off and on times from calendar events
+func (s *Service) getCalendarSchedule(ctx context.Context) (nextOff, nextOn *time.Time, err error) {
+	now := time.Now().In(s.location)
+
+	events, err := s.haClient.GetCalendarEvents(ctx, s.config.CalendarEntityID, now.Add(-calendarLookahead), now.Add(calendarLookahead))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+
+	nextOff, nextOn = homeassistant.NextOutageWindow(events, now, s.location)
+	return nextOff, nextOn, nil
+}
+
+// ScheduledOffTime returns the scheduled outage time within [from, to).
+// Only a calendar schedule covers past periods; ok is false without one.
+func (s *Service) ScheduledOffTime(ctx context.Context, from, to time.Time) (d time.Duration, ok bool, err error) {
+	if !s.config.IsCalendarScheduleEnabled() {
+		return 0, false, nil
+	}
+
+	events, err := s.haClient.GetCalendarEvents(ctx, s.config.CalendarEntityID, from, to)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+
+	return homeassistant.ScheduledOffTime(events, from, to, s.location), true, nil
+}
+
+// isPaused checks if notifications are paused from Telegram or via HA input_boolean
 func (s *Service) isPaused(ctx context.Context) bool {
-	if s.config.PauseEntityID == "" {
+	s.pauseMu.Lock()
+	internal := s.pause != nil && s.pause.Internal
+	s.pauseMu.Unlock()
+	if internal {
+		return true
+	}
+
+	if s.config.PauseEntityID == "" || s.states == nil {
 		return false
 	}
 
-	entity, err := s.haClient.GetState(ctx, s.config.PauseEntityID)
+	entity, err := s.states.GetState(ctx, s.config.PauseEntityID)
 	if err != nil {
 		logger.Debug("Failed to check pause state: %v", err)
 		return false
@@ -175,11 +304,24 @@ func (s *Service) isPaused(ctx context.Context) bool {
 
 // sendToAllChats sends message to all notification chat IDs
 func (s *Service) sendToAllChats(text string) error {
+	return s.sendToChats(s.config.NotificationChatIDs, text)
+}
+
+// sendToChats sends message to the given chat IDs
+func (s *Service) sendToChats(chatIDs []int64, text string) error {
+	return s.sendToChatsWithKeyboard(chatIDs, text, nil)
+}
+
+// sendToChatsWithKeyboard sends message with an optional inline keyboard to the given chat IDs
+func (s *Service) sendToChatsWithKeyboard(chatIDs []int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
 	var lastErr error
 
-	for _, chatID := range s.config.NotificationChatIDs {
+	for _, chatID := range chatIDs {
 		msg := tgbotapi.NewMessage(chatID, text)
 		msg.ParseMode = tgbotapi.ModeMarkdown
+		if keyboard != nil {
+			msg.ReplyMarkup = keyboard
+		}
 
 		if _, err := s.bot.Send(msg); err != nil {
 			logger.Error("Failed to send notification to chat %d: %v", chatID, err)
@@ -230,7 +372,7 @@ func (s *Service) NotifyScheduleChanged(ctx context.Context, scheduleType string
 
 	sb.WriteString("_за даними Yasno_")
 
-	return s.sendToAllChats(sb.String())
+	return s.sendWithPauseButton(sb.String())
 }
 
 // GetScheduledTime is a public wrapper for getScheduledTime
@@ -238,6 +380,54 @@ func (s *Service) GetScheduledTime(ctx context.Context, sensorID string) (*time.
 	return s.getScheduledTime(ctx, sensorID)
 }
 
+// HasSchedule checks if a schedule source is configured for scheduleType ("on" or "off")
+func (s *Service) HasSchedule(scheduleType string) bool {
+	return s.scheduleSensorID(scheduleType) != "" || s.config.IsCalendarScheduleEnabled()
+}
+
+// GetNextScheduledTime returns the next scheduled time for scheduleType ("on" or "off").
+// A configured next on/off sensor takes precedence over the calendar entity.
+func (s *Service) GetNextScheduledTime(ctx context.Context, scheduleType string) (*time.Time, error) {
+	if sensorID := s.scheduleSensorID(scheduleType); sensorID != "" {
+		return s.getScheduledTime(ctx, sensorID)
+	}
+
+	if !s.config.IsCalendarScheduleEnabled() {
+		return nil, nil
+	}
+
+	nextOff, nextOn, err := s.getCalendarSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if scheduleType == "on" {
+		return nextOn, nil
+	}
+	return nextOff, nil
+}
+
+// GetCalendarSchedule reads the calendar once and returns the next scheduled
+// power off and on times derived from it, regardless of configured sensors
+func (s *Service) GetCalendarSchedule(ctx context.Context) (nextOff, nextOn *time.Time, err error) {
+	if !s.config.IsCalendarScheduleEnabled() {
+		return nil, nil, nil
+	}
+	return s.getCalendarSchedule(ctx)
+}
+
+// scheduleSensorID returns the sensor configured for scheduleType
+func (s *Service) scheduleSensorID(scheduleType string) string {
+	if scheduleType == "on" {
+		return s.config.NextOnSensorID
+	}
+	return s.config.NextOffSensorID
+}
+
+// FormatDuration is a public wrapper for formatDuration
+func FormatDuration(d time.Duration) string {
+	return formatDuration(d)
+}
+
 // formatDuration formats duration in human-readable Ukrainian
 func formatDuration(d time.Duration) string {
 	if d < 0 {
@@ -0,0 +1,73 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// PowerStatus is a snapshot of the power state shown by the /power bot command
+type PowerStatus struct {
+	State    string        // "on", "off" or "unknown"
+	Since    time.Time     // When the state last changed; zero if unknown
+	Inferred bool          // Outage inferred from lost connectivity to Home Assistant
+	TodayOff time.Duration // Time without power since midnight
+}
+
+// Location returns the time zone used in messages
+func (s *Service) Location() *time.Location {
+	return s.location
+}
+
+// FormatPowerStatus builds the reply to the /power bot command
+func (s *Service) FormatPowerStatus(ctx context.Context, status PowerStatus) string {
+	now := time.Now().In(s.location)
+
+	var sb strings.Builder
+	var scheduleType string
+	switch status.State {
+	case "on":
+		sb.WriteString(fmt.Sprintf("%s *Світло є*", IconPowerOn))
+		scheduleType = "off"
+	case "off":
+		sb.WriteString(fmt.Sprintf("%s *Світла немає*", IconPowerOff))
+		appendInferredMarker(&sb, PowerEvent{Inferred: status.Inferred})
+		scheduleType = "on"
+	default:
+		sb.WriteString(fmt.Sprintf("%s *Стан світла невідомий*", IconWarning))
+	}
+
+	if !status.Since.IsZero() {
+		since := status.Since.In(s.location)
+		layout := "15:04"
+		if since.YearDay() != now.YearDay() || since.Year() != now.Year() {
+			layout = "02.01 15:04"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s З %s (*%s*)", IconTime, since.Format(layout), formatDuration(now.Sub(since))))
+	}
+
+	// Inferred outages mean Home Assistant is unreachable, the schedule can't be read
+	if scheduleType != "" && !status.Inferred && s.HasSchedule(scheduleType) {
+		next, err := s.GetNextScheduledTime(ctx, scheduleType)
+		if err != nil {
+			logger.Warn("Failed to get next %s time: %v", scheduleType, err)
+		} else if next != nil {
+			label := "Відключення"
+			if scheduleType == "on" {
+				label = "Заживлення"
+			}
+			sb.WriteString(fmt.Sprintf("\n\n%s %s через *%s* (%s)\n_за даними Yasno_",
+				IconSchedule,
+				label,
+				formatDuration(next.Sub(now)),
+				next.In(s.location).Format("15:04")))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n\n📊 Сьогодні без світла: *%s*", formatDuration(status.TodayOff)))
+
+	return sb.String()
+}
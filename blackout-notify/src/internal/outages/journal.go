@@ -0,0 +1,288 @@
+package outages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// retention is how long finished outages are kept in the journal
+const retention = 90 * 24 * time.Hour
+
+// Outage represents a single period without power
+type Outage struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Ongoing reports whether the outage has not ended yet
+func (o Outage) Ongoing() bool {
+	return o.End == nil
+}
+
+// Duration returns outage duration; ongoing outages are measured up to now
+func (o Outage) Duration(now time.Time) time.Duration {
+	if o.End != nil {
+		return o.End.Sub(o.Start)
+	}
+	return now.Sub(o.Start)
+}
+
+// Journal records outages and persists them to a JSON file
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	outages []Outage
+}
+
+// NewJournal creates a journal backed by the file at path.
+// An empty path keeps the journal in memory only.
+func NewJournal(path string) (*Journal, error) {
+	j := &Journal{path: path}
+
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &j.outages); err != nil {
+		return nil, fmt.Errorf("failed to decode journal: %w", err)
+	}
+
+	sort.Slice(j.outages, func(a, b int) bool {
+		return j.outages[a].Start.Before(j.outages[b].Start)
+	})
+
+	return j, nil
+}
+
+// Begin records the start of an outage. Does nothing if an outage is already ongoing.
+func (j *Journal) Begin(t time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.currentLocked() != nil {
+		return nil
+	}
+
+	j.outages = append(j.outages, Outage{Start: t})
+	return j.saveLocked(t)
+}
+
+// End records the end of the ongoing outage. Does nothing if there is none.
+func (j *Journal) End(t time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	current := j.currentLocked()
+	if current == nil {
+		return nil
+	}
+
+	if t.Before(current.Start) {
+		t = current.Start
+	}
+	current.End = &t
+	return j.saveLocked(t)
+}
+
+// Import adds outages reconstructed elsewhere (e.g. from HA history).
+// Outages overlapping already recorded ones are skipped.
+func (j *Journal) Import(imported []Outage) error {
+	if len(imported) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, o := range imported {
+		if j.overlapsLocked(o) {
+			continue
+		}
+		j.outages = append(j.outages, o)
+	}
+
+	sort.Slice(j.outages, func(a, b int) bool {
+		return j.outages[a].Start.Before(j.outages[b].Start)
+	})
+
+	return j.saveLocked(time.Now())
+}
+
+// IsEmpty reports whether no outages are recorded
+func (j *Journal) IsEmpty() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.outages) == 0
+}
+
+// Current returns a copy of the ongoing outage or nil
+func (j *Journal) Current() *Outage {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if current := j.currentLocked(); current != nil {
+		o := *current
+		return &o
+	}
+	return nil
+}
+
+// Last returns a copy of the most recent outage (ongoing or finished) or nil
+func (j *Journal) Last() *Outage {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.outages) == 0 {
+		return nil
+	}
+	o := j.outages[len(j.outages)-1]
+	return &o
+}
+
+// LastFinished returns a copy of the most recent finished outage or nil
+func (j *Journal) LastFinished() *Outage {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := len(j.outages) - 1; i >= 0; i-- {
+		if !j.outages[i].Ongoing() {
+			o := j.outages[i]
+			return &o
+		}
+	}
+	return nil
+}
+
+// Between returns outages overlapping the [from, to) interval, oldest first
+func (j *Journal) Between(from, to time.Time) []Outage {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var result []Outage
+	for _, o := range j.outages {
+		if !o.Start.Before(to) {
+			continue
+		}
+		if o.End != nil && !o.End.After(from) {
+			continue
+		}
+		result = append(result, o)
+	}
+	return result
+}
+
+// TotalOff returns time without power within [from, to), ongoing outages counted up to now
+func (j *Journal) TotalOff(from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, o := range j.Between(from, to) {
+		start := o.Start
+		if start.Before(from) {
+			start = from
+		}
+		end := now
+		if o.End != nil {
+			end = *o.End
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// Stats summarizes outages within a period
+type Stats struct {
+	Count   int           // Outages overlapping the period
+	Total   time.Duration // Time without power within the period
+	Longest time.Duration // Full duration of the longest outage
+}
+
+// Stats returns outage statistics for [from, to), ongoing outages counted up to now
+func (j *Journal) Stats(from, to, now time.Time) Stats {
+	list := j.Between(from, to)
+
+	stats := Stats{
+		Count: len(list),
+		Total: j.TotalOff(from, to, now),
+	}
+	for _, o := range list {
+		stats.Longest = max(stats.Longest, o.Duration(now))
+	}
+	return stats
+}
+
+// overlapsLocked checks if o overlaps any recorded outage; caller must hold j.mu
+func (j *Journal) overlapsLocked(o Outage) bool {
+	for _, existing := range j.outages {
+		if o.End != nil && !o.End.After(existing.Start) {
+			continue
+		}
+		if existing.End != nil && !existing.End.After(o.Start) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// currentLocked returns the ongoing outage; caller must hold j.mu
+func (j *Journal) currentLocked() *Outage {
+	if n := len(j.outages); n > 0 && j.outages[n-1].Ongoing() {
+		return &j.outages[n-1]
+	}
+	return nil
+}
+
+// saveLocked prunes old outages and writes the journal to disk; caller must hold j.mu
+func (j *Journal) saveLocked(now time.Time) error {
+	cutoff := now.Add(-retention)
+	kept := j.outages[:0]
+	for _, o := range j.outages {
+		if o.End != nil && o.End.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, o)
+	}
+	j.outages = kept
+
+	if j.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(j.outages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	// Write to a temp file first so a power loss can't leave a truncated journal
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package outages
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalBeginEnd(t *testing.T) {
+	j, err := NewJournal("")
+	if err != nil {
+		t.Fatalf("NewJournal() error = %v", err)
+	}
+
+	start := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	// End without ongoing outage is a no-op
+	if err := j.End(start); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	if j.Last() != nil {
+		t.Fatal("Expected empty journal after End() without outage")
+	}
+
+	if err := j.Begin(start); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	// Second Begin while ongoing is ignored
+	if err := j.Begin(start.Add(time.Minute)); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	current := j.Current()
+	if current == nil || !current.Start.Equal(start) {
+		t.Fatalf("Current() = %v, want outage started at %v", current, start)
+	}
+
+	if err := j.End(end); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+
+	if j.Current() != nil {
+		t.Error("Current() should be nil after End()")
+	}
+
+	last := j.LastFinished()
+	if last == nil {
+		t.Fatal("LastFinished() = nil")
+	}
+	if got := last.Duration(end); got != 2*time.Hour {
+		t.Errorf("Duration() = %v, want 2h", got)
+	}
+}
+
+func TestJournalTotalOff(t *testing.T) {
+	j, _ := NewJournal("")
+	day := time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	// Outage crossing midnight: only the part inside the day counts
+	_ = j.Begin(day.Add(-time.Hour))
+	_ = j.End(day.Add(time.Hour))
+
+	_ = j.Begin(day.Add(10 * time.Hour))
+	_ = j.End(day.Add(12 * time.Hour))
+
+	// Ongoing outage counted up to now
+	_ = j.Begin(day.Add(20 * time.Hour))
+	now := day.Add(20*time.Hour + 30*time.Minute)
+
+	got := j.TotalOff(day, day.Add(24*time.Hour), now)
+	want := 3*time.Hour + 30*time.Minute
+	if got != want {
+		t.Errorf("TotalOff() = %v, want %v", got, want)
+	}
+
+	if n := len(j.Between(day.Add(11*time.Hour), day.Add(13*time.Hour))); n != 1 {
+		t.Errorf("Between() returned %d outages, want 1", n)
+	}
+
+	stats := j.Stats(day, day.Add(24*time.Hour), now)
+	if stats.Count != 3 || stats.Total != want || stats.Longest != 2*time.Hour {
+		t.Errorf("Stats() = %+v, want 3 outages, %v total, 2h longest", stats, want)
+	}
+}
+
+func TestJournalPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outages.json")
+
+	j, err := NewJournal(path)
+	if err != nil {
+		t.Fatalf("NewJournal() error = %v", err)
+	}
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := j.Begin(start); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	reloaded, err := NewJournal(path)
+	if err != nil {
+		t.Fatalf("NewJournal() reload error = %v", err)
+	}
+
+	current := reloaded.Current()
+	if current == nil || !current.Start.Equal(start) {
+		t.Errorf("Reloaded Current() = %v, want outage started at %v", current, start)
+	}
+}
+
+func TestJournalImport(t *testing.T) {
+	j, _ := NewJournal("")
+	base := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+
+	_ = j.Begin(base.Add(10 * time.Hour))
+	_ = j.End(base.Add(12 * time.Hour))
+
+	end1 := base.Add(3 * time.Hour)
+	end2 := base.Add(11 * time.Hour)
+	err := j.Import([]Outage{
+		{Start: base.Add(time.Hour), End: &end1},
+		{Start: base.Add(9 * time.Hour), End: &end2}, // overlaps recorded outage
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	all := j.Between(base, base.Add(24*time.Hour))
+	if len(all) != 2 {
+		t.Fatalf("Between() returned %d outages, want 2", len(all))
+	}
+
+	if !all[0].Start.Equal(base.Add(time.Hour)) {
+		t.Errorf("First outage start = %v, want %v", all[0].Start, base.Add(time.Hour))
+	}
+}
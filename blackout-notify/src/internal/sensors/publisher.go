@@ -0,0 +1,158 @@
+package sensors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+	"github.com/yourusername/haaddon/telegram-bot/internal/outages"
+)
+
+// Entity IDs of sensors maintained by the add-on
+const (
+	EntityOutageDuration   = "sensor.blackout_notify_outage_duration"
+	EntityLastOutageStart  = "sensor.blackout_notify_last_outage_start"
+	EntityTodayOffMinutes  = "sensor.blackout_notify_today_off_minutes"
+	EntityConnected        = "binary_sensor.blackout_notify_connected"
+	defaultRefreshInterval = 30 * time.Second
+)
+
+// Publisher writes add-on computed sensors to Home Assistant.
+// HA forgets states written via the REST API on restart, so sensors are
+// republished periodically in addition to every power transition.
+type Publisher struct {
+	haClient *homeassistant.Client
+	wsClient *homeassistant.WSClient
+	journal  *outages.Journal
+	location *time.Location
+	interval time.Duration
+	mu       sync.Mutex
+}
+
+// NewPublisher creates a new sensor publisher
+func NewPublisher(cfg *config.Config, haClient *homeassistant.Client, wsClient *homeassistant.WSClient, journal *outages.Journal) *Publisher {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Warn("Failed to load timezone %s, using UTC: %v", cfg.Timezone, err)
+		loc = time.UTC
+	}
+
+	interval := time.Duration(cfg.PollingInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+
+	return &Publisher{
+		haClient: haClient,
+		wsClient: wsClient,
+		journal:  journal,
+		location: loc,
+		interval: interval,
+	}
+}
+
+// Run republishes sensors periodically until ctx is cancelled,
+// then marks the add-on as disconnected
+func (p *Publisher) Run(ctx context.Context) {
+	p.Publish(ctx)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := p.publishConnected(shutdownCtx, false); err != nil {
+				logger.Debug("Failed to publish disconnected state: %v", err)
+			}
+			return
+		case <-ticker.C:
+			p.Publish(ctx)
+		}
+	}
+}
+
+// Publish writes all sensors with current values
+func (p *Publisher) Publish(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now().In(p.location)
+
+	if err := p.publishConnected(ctx, p.wsClient.IsConnected()); err != nil {
+		logger.Warn("Failed to publish sensors: %v", err)
+		return
+	}
+
+	// Current outage if ongoing, otherwise the last finished one
+	outage := p.journal.Last()
+
+	durationState := "0"
+	lastStartState := "unknown"
+	ongoing := false
+	if outage != nil {
+		durationState = fmt.Sprintf("%d", int(outage.Duration(now).Minutes()))
+		lastStartState = outage.Start.In(p.location).Format(time.RFC3339)
+		ongoing = outage.Ongoing()
+	}
+
+	p.setState(ctx, EntityOutageDuration, durationState, map[string]interface{}{
+		"friendly_name":       "Outage duration",
+		"unit_of_measurement": "min",
+		"device_class":        "duration",
+		"icon":                "mdi:timer-outline",
+		"ongoing":             ongoing,
+	})
+
+	p.setState(ctx, EntityLastOutageStart, lastStartState, map[string]interface{}{
+		"friendly_name": "Last outage start",
+		"device_class":  "timestamp",
+		"icon":          "mdi:power-plug-off",
+	})
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, p.location)
+	todayOff := p.journal.TotalOff(midnight, midnight.AddDate(0, 0, 1), now)
+	p.setState(ctx, EntityTodayOffMinutes, fmt.Sprintf("%d", int(todayOff.Minutes())), map[string]interface{}{
+		"friendly_name":       "Time without power today",
+		"unit_of_measurement": "min",
+		"device_class":        "duration",
+		"icon":                "mdi:clock-alert-outline",
+	})
+}
+
+// publishConnected writes the add-on connectivity binary sensor
+func (p *Publisher) publishConnected(ctx context.Context, connected bool) error {
+	state := "off"
+	if connected {
+		state = "on"
+	}
+
+	attributes := map[string]interface{}{
+		"friendly_name": "Blackout Notify connected",
+		"device_class":  "connectivity",
+	}
+	if stats := p.wsClient.DispatchStats(); stats.Dropped > 0 || stats.Panics > 0 {
+		attributes["dropped_events"] = stats.Dropped
+		attributes["handler_panics"] = stats.Panics
+	}
+	if latency, at := p.wsClient.LastPong(); connected && !at.IsZero() {
+		attributes["ping_latency_ms"] = latency.Milliseconds()
+		attributes["last_pong"] = at.In(p.location).Format(time.RFC3339)
+	}
+
+	_, err := p.haClient.SetState(ctx, EntityConnected, state, attributes)
+	return err
+}
+
+// setState writes a single sensor, logging failures
+func (p *Publisher) setState(ctx context.Context, entityID, state string, attributes map[string]interface{}) {
+	if _, err := p.haClient.SetState(ctx, entityID, state, attributes); err != nil {
+		logger.Warn("Failed to publish %s: %v", entityID, err)
+	}
+}
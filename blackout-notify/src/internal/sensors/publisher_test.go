@@ -0,0 +1,244 @@
+package sensors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/outages"
+)
+
+// postedState is a state written through the REST API
+type postedState struct {
+	entityID string
+	update   homeassistant.StateUpdate
+}
+
+// statesRecorder is a fake Home Assistant recording the states POSTed to it
+type statesRecorder struct {
+	mu     sync.Mutex
+	states []postedState
+}
+
+func newStatesRecorder(t *testing.T) (*statesRecorder, *homeassistant.Client) {
+	rec := &statesRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/states/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var update homeassistant.StateUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			t.Errorf("Invalid state update: %v", err)
+		}
+		entityID := strings.TrimPrefix(r.URL.Path, "/states/")
+
+		rec.mu.Lock()
+		rec.states = append(rec.states, postedState{entityID, update})
+		rec.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(homeassistant.Entity{EntityID: entityID, State: update.State})
+	}))
+	t.Cleanup(server.Close)
+
+	return rec, homeassistant.NewClient(server.URL, "test_token")
+}
+
+// posted returns the states written so far
+func (r *statesRecorder) posted() []postedState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]postedState(nil), r.states...)
+}
+
+// latest returns the last state written for each entity
+func (r *statesRecorder) latest() map[string]homeassistant.StateUpdate {
+	latest := make(map[string]homeassistant.StateUpdate)
+	for _, s := range r.posted() {
+		latest[s.entityID] = s.update
+	}
+	return latest
+}
+
+// connectedWS returns a WebSocket client authenticated against a fake Home Assistant
+func connectedWS(t *testing.T) *homeassistant.WSClient {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteJSON(map[string]interface{}{"type": homeassistant.MsgTypeAuthRequired})
+		var auth map[string]interface{}
+		if err := conn.ReadJSON(&auth); err != nil {
+			return
+		}
+		conn.WriteJSON(map[string]interface{}{"type": homeassistant.MsgTypeAuthOK})
+
+		var msg map[string]interface{}
+		conn.ReadJSON(&msg)
+	}))
+	t.Cleanup(server.Close)
+
+	ws := homeassistant.NewWSClient(server.URL+"/api", "test_token")
+	if err := ws.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func TestPublish(t *testing.T) {
+	now := time.Now().UTC()
+	start := now.Add(-90 * time.Minute)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	todayOff := int(min(90*time.Minute, now.Sub(midnight)).Minutes())
+
+	tests := []struct {
+		name      string
+		connected bool
+		outage    bool
+		want      map[string]string
+	}{
+		{
+			name: "no outages, disconnected",
+			want: map[string]string{
+				EntityConnected:       "off",
+				EntityOutageDuration:  "0",
+				EntityLastOutageStart: "unknown",
+				EntityTodayOffMinutes: "0",
+			},
+		},
+		{
+			name:      "ongoing outage, connected",
+			connected: true,
+			outage:    true,
+			want: map[string]string{
+				EntityConnected:       "on",
+				EntityOutageDuration:  "90",
+				EntityLastOutageStart: start.Format(time.RFC3339),
+				EntityTodayOffMinutes: strconv.Itoa(todayOff),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, client := newStatesRecorder(t)
+			ws := homeassistant.NewWSClient("http://localhost:8123/api", "test_token")
+			if tt.connected {
+				ws = connectedWS(t)
+			}
+			journal, _ := outages.NewJournal("")
+			if tt.outage {
+				if err := journal.Begin(start); err != nil {
+					t.Fatalf("Begin() error = %v", err)
+				}
+			}
+
+			p := NewPublisher(&config.Config{Timezone: "UTC"}, client, ws, journal)
+			p.Publish(context.Background())
+
+			latest := rec.latest()
+			if len(latest) != len(tt.want) {
+				t.Errorf("Published %d sensors, want %d", len(latest), len(tt.want))
+			}
+			for entityID, want := range tt.want {
+				if got := latest[entityID].State; got != want {
+					t.Errorf("%s = %q, want %q", entityID, got, want)
+				}
+			}
+
+			connected := latest[EntityConnected].Attributes
+			if connected["device_class"] != "connectivity" || connected["friendly_name"] != "Blackout Notify connected" {
+				t.Errorf("%s attributes = %v", EntityConnected, connected)
+			}
+			if _, ok := connected["dropped_events"]; ok {
+				t.Errorf("%s attributes = %v, want no dispatch counters without drops", EntityConnected, connected)
+			}
+
+			duration := latest[EntityOutageDuration].Attributes
+			if duration["ongoing"] != tt.outage || duration["unit_of_measurement"] != "min" || duration["device_class"] != "duration" {
+				t.Errorf("%s attributes = %v", EntityOutageDuration, duration)
+			}
+			if latest[EntityLastOutageStart].Attributes["device_class"] != "timestamp" {
+				t.Errorf("%s attributes = %v", EntityLastOutageStart, latest[EntityLastOutageStart].Attributes)
+			}
+		})
+	}
+}
+
+func TestRunRepublishes(t *testing.T) {
+	rec, client := newStatesRecorder(t)
+	ws := connectedWS(t)
+	journal, _ := outages.NewJournal("")
+
+	p := NewPublisher(&config.Config{Timezone: "UTC"}, client, ws, journal)
+	p.interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for count(rec.posted(), EntityOutageDuration) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Sensors published %d times, want at least 3", count(rec.posted(), EntityOutageDuration))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// Stopping marks the add-on as disconnected
+	posted := rec.posted()
+	if last := posted[len(posted)-1]; last.entityID != EntityConnected || last.update.State != "off" {
+		t.Errorf("Last published %s = %q, want %s = off", last.entityID, last.update.State, EntityConnected)
+	}
+}
+
+func TestNewPublisherIntervalDefault(t *testing.T) {
+	tests := []struct {
+		pollingInterval int
+		want            time.Duration
+	}{
+		{60, time.Minute},
+		{0, defaultRefreshInterval},
+		{-5, defaultRefreshInterval},
+	}
+
+	for _, tt := range tests {
+		p := NewPublisher(&config.Config{Timezone: "UTC", PollingInterval: tt.pollingInterval}, nil, nil, nil)
+		if p.interval != tt.want {
+			t.Errorf("NewPublisher(PollingInterval: %d).interval = %v, want %v", tt.pollingInterval, p.interval, tt.want)
+		}
+	}
+}
+
+// count returns how often the entity was published
+func count(posted []postedState, entityID string) int {
+	n := 0
+	for _, s := range posted {
+		if s.entityID == entityID {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,170 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
+)
+
+// handleConnectionEvent tracks Home Assistant connectivity and alerts admins
+// when the connection stays down longer than connection_alert_minutes
+func (w *Watcher) handleConnectionEvent(event homeassistant.ConnectionEvent) {
+	switch event.Type {
+	case homeassistant.ConnEventDisconnected:
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		// Failed resubscribe attempts don't restart the outage clock
+		if !w.disconnectedAt.IsZero() {
+			return
+		}
+		w.disconnectedAt = event.At
+		if w.connAlertAfter > 0 {
+			w.connAlertTimer = time.AfterFunc(w.connAlertAfter, w.alertConnectionLost)
+		}
+
+	case homeassistant.ConnEventConnected:
+		w.mu.Lock()
+		downSince := w.disconnectedAt
+		alerted := w.connAlertSent
+		w.disconnectedAt = time.Time{}
+		w.connAlertSent = false
+		w.authAlertSent = false
+		if w.connAlertTimer != nil {
+			w.connAlertTimer.Stop()
+			w.connAlertTimer = nil
+		}
+		w.mu.Unlock()
+
+		if !downSince.IsZero() {
+			logger.Info("Connection to Home Assistant restored after %v", event.At.Sub(downSince).Round(time.Second))
+		}
+		if alerted {
+			go func() {
+				if err := w.notifSvc.NotifyConnectionRestored(event.At.Sub(downSince)); err != nil {
+					logger.Error("Failed to send connection restored alert: %v", err)
+				}
+			}()
+		}
+
+	case homeassistant.ConnEventAuthFailed:
+		w.mu.Lock()
+		alerted := w.authAlertSent
+		w.authAlertSent = true
+		w.mu.Unlock()
+
+		// Retries keep failing the same way; alert once until the next successful connection
+		if alerted {
+			return
+		}
+		go func() {
+			if err := w.notifSvc.NotifyAuthFailed(); err != nil {
+				logger.Error("Failed to send auth failure alert: %v", err)
+			}
+		}()
+
+	case homeassistant.ConnEventResubscribed:
+		logger.Info("State subscription restored, missed changes will be replayed")
+	}
+}
+
+// alertConnectionLost sends the connection lost alert if still disconnected
+func (w *Watcher) alertConnectionLost() {
+	w.mu.Lock()
+	downSince := w.disconnectedAt
+	if downSince.IsZero() || w.connAlertSent {
+		w.mu.Unlock()
+		return
+	}
+	w.connAlertSent = true
+	w.mu.Unlock()
+
+	if err := w.notifSvc.NotifyConnectionLost(time.Since(downSince)); err != nil {
+		logger.Error("Failed to send connection lost alert: %v", err)
+	}
+}
+
+// inferPowerState treats a prolonged loss of connection to Home Assistant as a
+// power outage when the add-on runs remotely (infer_power_from_connection).
+// Power is considered off connection_off_minutes after the disconnect and on
+// again once the connection has been stable for connection_on_minutes.
+func (w *Watcher) inferPowerState(ctx context.Context, event homeassistant.ConnectionEvent) {
+	if !w.config.InferPowerFromConnection {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch event.Type {
+	case homeassistant.ConnEventDisconnected:
+		// A pending "power on" is cancelled by another disconnect
+		if w.inferTimer != nil {
+			w.inferTimer.Stop()
+			w.inferTimer = nil
+		}
+		if w.inferredOff || w.lastState == PowerStateOff {
+			return
+		}
+
+		disconnectedAt := event.At
+		w.inferTimer = time.AfterFunc(w.connOffAfter, func() {
+			if w.wsClient.IsConnected() {
+				return
+			}
+			// The outage started when the connection dropped, not when the threshold passed
+			w.setPowerState(ctx, PowerStateOff, disconnectedAt, true)
+		})
+
+	case homeassistant.ConnEventConnected:
+		if w.inferTimer != nil {
+			w.inferTimer.Stop()
+			w.inferTimer = nil
+		}
+
+		// Without a sensor, the first connection establishes the initial state
+		if w.lastState == PowerStateUnknown && w.config.WatchedEntityID == "" {
+			go w.setPowerState(ctx, PowerStateOn, event.At, true)
+			return
+		}
+		if !w.inferredOff {
+			return
+		}
+
+		connectedAt := event.At
+		w.inferTimer = time.AfterFunc(w.connOnAfter, func() {
+			if !w.wsClient.IsConnected() {
+				return
+			}
+			w.confirmPowerRestored(ctx, connectedAt)
+		})
+	}
+}
+
+// inferUntilConnected starts the power off timer at startup. RunWithReconnect
+// only reports a disconnect after a successful connection, so Home Assistant
+// being unreachable from the start would never be inferred as an outage.
+func (w *Watcher) inferUntilConnected(ctx context.Context) {
+	w.inferPowerState(ctx, homeassistant.ConnectionEvent{Type: homeassistant.ConnEventDisconnected, At: time.Now()})
+}
+
+// confirmPowerRestored ends an inferred outage after the connection came back.
+// A watched sensor, if configured, is authoritative: HA may have been reachable
+// again while the grid is still down (e.g. HA on a UPS).
+func (w *Watcher) confirmPowerRestored(ctx context.Context, connectedAt time.Time) {
+	if w.config.WatchedEntityID != "" {
+		entity, err := w.haClient.GetState(ctx, w.config.WatchedEntityID)
+		if err == nil {
+			if state := normalizeState(entity.State); state != PowerStateUnknown {
+				w.setPowerState(ctx, state, time.Now(), false)
+				return
+			}
+		} else {
+			logger.Warn("Failed to read %s after reconnect, assuming power is on: %v", w.config.WatchedEntityID, err)
+		}
+	}
+
+	w.setPowerState(ctx, PowerStateOn, connectedAt, true)
+}
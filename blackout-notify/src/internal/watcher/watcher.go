@@ -9,6 +9,8 @@ import (
 	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
 	"github.com/yourusername/haaddon/telegram-bot/internal/logger"
 	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
+	"github.com/yourusername/haaddon/telegram-bot/internal/outages"
+	"github.com/yourusername/haaddon/telegram-bot/internal/sensors"
 )
 
 // PowerState represents power status
@@ -20,19 +22,42 @@ const (
 	PowerStateUnknown PowerState = "unknown"
 )
 
+// Status is the current power state as seen by the watcher
+type Status struct {
+	State    PowerState
+	Since    time.Time // Zero if the state is unknown
+	Inferred bool      // Outage inferred from lost connectivity to Home Assistant
+}
+
 // Watcher monitors entity state changes and triggers notifications
 type Watcher struct {
 	config             *config.Config
 	wsClient           *homeassistant.WSClient
 	haClient           *homeassistant.Client
 	notifSvc           *notifications.Service
+	journal            *outages.Journal
+	publisher          *sensors.Publisher
 	lastState          PowerState
+	stateSince         time.Time
 	lastNextOnTime     *time.Time
 	lastNextOffTime    *time.Time
 	mu                 sync.Mutex
 	debounceTime       time.Duration
 	lastChange         time.Time
 	lastScheduleChange time.Time
+
+	// Connection tracking for admin alerts
+	connAlertAfter time.Duration
+	connAlertTimer *time.Timer
+	disconnectedAt time.Time
+	connAlertSent  bool
+	authAlertSent  bool
+
+	// Remote mode: power state inferred from connectivity
+	connOffAfter time.Duration
+	connOnAfter  time.Duration
+	inferTimer   *time.Timer
+	inferredOff  bool
 }
 
 // NewWatcher creates a new state watcher
@@ -41,38 +66,56 @@ func NewWatcher(
 	wsClient *homeassistant.WSClient,
 	haClient *homeassistant.Client,
 	notifSvc *notifications.Service,
+	journal *outages.Journal,
+	publisher *sensors.Publisher,
 ) *Watcher {
 	return &Watcher{
 		config:       cfg,
 		wsClient:     wsClient,
 		haClient:     haClient,
 		notifSvc:     notifSvc,
+		journal:      journal,
+		publisher:    publisher,
 		lastState:    PowerStateUnknown,
 		debounceTime: 5 * time.Second, // Debounce to avoid rapid state changes
+
+		connAlertAfter: time.Duration(cfg.ConnectionAlertMinutes) * time.Minute,
+		connOffAfter:   time.Duration(cfg.ConnectionOffMinutes) * time.Minute,
+		connOnAfter:    time.Duration(cfg.ConnectionOnMinutes) * time.Minute,
 	}
 }
 
 // Start initializes the watcher and begins monitoring
 func (w *Watcher) Start(ctx context.Context) error {
-	if w.config.WatchedEntityID == "" {
+	if w.config.WatchedEntityID == "" && !w.config.InferPowerFromConnection {
 		logger.Info("No watched entity configured, power monitoring disabled")
 		return nil
 	}
 
-	logger.Info("Starting power watcher for entity: %s", w.config.WatchedEntityID)
+	if w.config.WatchedEntityID != "" {
+		logger.Info("Starting power watcher for entity: %s", w.config.WatchedEntityID)
+
+		// Reconstruct past outages from HA history on first start
+		w.backfillJournal(ctx)
 
-	// Get initial state
-	if err := w.fetchInitialState(ctx); err != nil {
-		logger.Warn("Failed to get initial state: %v", err)
+		// Get initial state
+		if err := w.fetchInitialState(ctx); err != nil {
+			logger.Warn("Failed to get initial state: %v", err)
+		}
+	}
+	if w.config.InferPowerFromConnection {
+		logger.Info("Inferring power state from connectivity (off after %v without connection)", w.connOffAfter)
 	}
 
 	// Get initial schedule times
 	w.fetchInitialScheduleTimes(ctx)
 
 	// Register handler for power state changes
-	w.wsClient.OnStateChange(w.config.WatchedEntityID, func(entityID string, oldState, newState *homeassistant.Entity) {
-		w.handleStateChange(ctx, oldState, newState)
-	})
+	if w.config.WatchedEntityID != "" {
+		w.wsClient.OnStateChange(w.config.WatchedEntityID, func(entityID string, oldState, newState *homeassistant.Entity) {
+			w.handleStateChange(ctx, oldState, newState)
+		})
+	}
 
 	// Register handlers for schedule changes
 	if w.config.NextOnSensorID != "" {
@@ -86,6 +129,21 @@ func (w *Watcher) Start(ctx context.Context) error {
 		})
 	}
 
+	// Calendar schedule: react to event boundaries and poll for edited events
+	if w.usesCalendarSchedule() {
+		w.wsClient.OnStateChange(w.config.CalendarEntityID, func(entityID string, oldState, newState *homeassistant.Entity) {
+			w.handleCalendarChange(ctx, oldState, newState)
+		})
+		go w.pollCalendar(ctx)
+	}
+
+	// Track connectivity to alert admins and, in remote mode, to infer power state
+	w.wsClient.OnConnectionEvent(func(event homeassistant.ConnectionEvent) {
+		w.handleConnectionEvent(event)
+		w.inferPowerState(ctx, event)
+	})
+	w.inferUntilConnected(ctx)
+
 	// Start WebSocket client with reconnect
 	return w.wsClient.RunWithReconnect(ctx)
 }
@@ -97,22 +155,142 @@ func (w *Watcher) fetchInitialState(ctx context.Context) error {
 		return err
 	}
 
+	state := normalizeState(entity.State)
+
+	changedAt, err := time.Parse(time.RFC3339, entity.LastChanged)
+	if err != nil {
+		changedAt = time.Now()
+	}
+
 	w.mu.Lock()
-	w.lastState = normalizeState(entity.State)
+	w.lastState = state
+	w.stateSince = changedAt
 	w.mu.Unlock()
 
-	logger.Info("Initial power state: %s", w.lastState)
+	logger.Info("Initial power state: %s", state)
+
+	// Reconcile the journal with transitions that happened while the add-on was down
+	w.recordTransition(state, changedAt)
+
 	return nil
 }
 
+// backfillJournal fills an empty outage journal from Home Assistant history
+func (w *Watcher) backfillJournal(ctx context.Context) {
+	if w.journal == nil || w.config.HistoryBackfillDays <= 0 || !w.journal.IsEmpty() {
+		return
+	}
+
+	start := time.Now().AddDate(0, 0, -w.config.HistoryBackfillDays)
+	history, err := w.haClient.GetHistory(ctx, start, homeassistant.HistoryOptions{
+		EntityIDs:              []string{w.config.WatchedEntityID},
+		MinimalResponse:        true,
+		NoAttributes:           true,
+		SignificantChangesOnly: true,
+	})
+	if err != nil {
+		logger.Warn("Failed to backfill outage journal: %v", err)
+		return
+	}
+
+	var states []homeassistant.Entity
+	if len(history) > 0 {
+		states = history[0]
+	}
+
+	imported := outagesFromHistory(states)
+	if err := w.journal.Import(imported); err != nil {
+		logger.Warn("Failed to save backfilled outages: %v", err)
+		return
+	}
+
+	logger.Info("Backfilled %d outage(s) from the last %d day(s) of history", len(imported), w.config.HistoryBackfillDays)
+}
+
+// outagesFromHistory reconstructs outages from state history (oldest first).
+// Unknown states neither start nor end an outage.
+func outagesFromHistory(states []homeassistant.Entity) []outages.Outage {
+	var result []outages.Outage
+	var current *outages.Outage
+
+	for _, entity := range states {
+		changedAt, err := time.Parse(time.RFC3339, entity.LastChanged)
+		if err != nil {
+			continue
+		}
+
+		switch normalizeState(entity.State) {
+		case PowerStateOff:
+			if current == nil {
+				current = &outages.Outage{Start: changedAt}
+			}
+		case PowerStateOn:
+			if current != nil {
+				end := changedAt
+				current.End = &end
+				result = append(result, *current)
+				current = nil
+			}
+		}
+	}
+
+	if current != nil {
+		result = append(result, *current)
+	}
+
+	return result
+}
+
+// recordTransition updates the outage journal for a power state change
+func (w *Watcher) recordTransition(state PowerState, at time.Time) {
+	if w.journal == nil {
+		return
+	}
+
+	var err error
+	switch state {
+	case PowerStateOff:
+		err = w.journal.Begin(at)
+	case PowerStateOn:
+		err = w.journal.End(at)
+	}
+
+	if err != nil {
+		logger.Warn("Failed to record outage: %v", err)
+	}
+}
+
+// lastOutageDuration returns the duration of the most recently finished outage
+func (w *Watcher) lastOutageDuration() time.Duration {
+	if w.journal == nil {
+		return 0
+	}
+
+	if outage := w.journal.LastFinished(); outage != nil {
+		return outage.Duration(time.Now())
+	}
+	return 0
+}
+
+// publishSensors refreshes add-on sensors in Home Assistant
+func (w *Watcher) publishSensors(ctx context.Context) {
+	if w.publisher != nil {
+		w.publisher.Publish(ctx)
+	}
+}
+
 // handleStateChange processes state change events
 func (w *Watcher) handleStateChange(ctx context.Context, oldState, newState *homeassistant.Entity) {
 	if newState == nil {
 		return
 	}
 
-	newPowerState := normalizeState(newState.State)
+	w.setPowerState(ctx, normalizeState(newState.State), time.Now(), false)
+}
 
+// setPowerState applies a power transition that happened at the given time.
+// inferred marks transitions derived from connectivity to HA rather than a sensor.
+func (w *Watcher) setPowerState(ctx context.Context, newPowerState PowerState, at time.Time, inferred bool) {
 	w.mu.Lock()
 	previousState := w.lastState
 	timeSinceLastChange := time.Since(w.lastChange)
@@ -129,14 +307,23 @@ func (w *Watcher) handleStateChange(ctx context.Context, oldState, newState *hom
 		return
 	}
 
-	logger.Info("Power state changed: %s -> %s", previousState, newPowerState)
+	if inferred {
+		logger.Info("Power state changed: %s -> %s (inferred from connectivity)", previousState, newPowerState)
+	} else {
+		logger.Info("Power state changed: %s -> %s", previousState, newPowerState)
+	}
 
 	// Update state
 	w.mu.Lock()
 	w.lastState = newPowerState
-	w.lastChange = time.Now()
+	w.stateSince = at
+	w.lastChange = at
+	w.inferredOff = inferred && newPowerState == PowerStateOff
 	w.mu.Unlock()
 
+	w.recordTransition(newPowerState, at)
+	defer w.publishSensors(ctx)
+
 	// Skip notification if transitioning from unknown state
 	if previousState == PowerStateUnknown {
 		logger.Info("State transition from unknown to %s, skipping notification (initial state detection)", newPowerState)
@@ -146,11 +333,11 @@ func (w *Watcher) handleStateChange(ctx context.Context, oldState, newState *hom
 	// Send notification based on new state
 	switch newPowerState {
 	case PowerStateOn:
-		if err := w.notifSvc.NotifyPowerOn(ctx); err != nil {
+		if err := w.notifSvc.NotifyPowerOn(ctx, w.lastOutageDuration(), inferred); err != nil {
 			logger.Error("Failed to send power on notification: %v", err)
 		}
 	case PowerStateOff:
-		if err := w.notifSvc.NotifyPowerOff(ctx); err != nil {
+		if err := w.notifSvc.NotifyPowerOff(ctx, inferred); err != nil {
 			logger.Error("Failed to send power off notification: %v", err)
 		}
 	}
@@ -163,17 +350,51 @@ func (w *Watcher) GetCurrentState() PowerState {
 	return w.lastState
 }
 
+// Status returns the current power state and when it was entered
+func (w *Watcher) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := Status{State: w.lastState, Inferred: w.inferredOff}
+	if w.lastState != PowerStateUnknown {
+		status.Since = w.stateSince
+	}
+	return status
+}
+
+// Journal returns the outage journal, nil if none is kept
+func (w *Watcher) Journal() *outages.Journal {
+	return w.journal
+}
+
+// TotalOff returns the time without power between from and to
+func (w *Watcher) TotalOff(from, to time.Time) time.Duration {
+	if w.journal == nil {
+		return 0
+	}
+	return w.journal.TotalOff(from, to, time.Now())
+}
+
 // Stop stops the watcher
 func (w *Watcher) Stop() {
 	if w.wsClient != nil {
 		w.wsClient.Stop()
 	}
+
+	w.mu.Lock()
+	if w.connAlertTimer != nil {
+		w.connAlertTimer.Stop()
+	}
+	if w.inferTimer != nil {
+		w.inferTimer.Stop()
+	}
+	w.mu.Unlock()
 }
 
 // fetchInitialScheduleTimes gets current schedule times
 func (w *Watcher) fetchInitialScheduleTimes(ctx context.Context) {
-	if w.config.NextOnSensorID != "" {
-		nextOn, err := w.notifSvc.GetScheduledTime(ctx, w.config.NextOnSensorID)
+	if w.notifSvc.HasSchedule("on") {
+		nextOn, err := w.notifSvc.GetNextScheduledTime(ctx, "on")
 		if err != nil {
 			logger.Warn("Failed to get initial next on time: %v", err)
 		} else {
@@ -186,8 +407,8 @@ func (w *Watcher) fetchInitialScheduleTimes(ctx context.Context) {
 		}
 	}
 
-	if w.config.NextOffSensorID != "" {
-		nextOff, err := w.notifSvc.GetScheduledTime(ctx, w.config.NextOffSensorID)
+	if w.notifSvc.HasSchedule("off") {
+		nextOff, err := w.notifSvc.GetNextScheduledTime(ctx, "off")
 		if err != nil {
 			logger.Warn("Failed to get initial next off time: %v", err)
 		} else {
@@ -207,32 +428,101 @@ func (w *Watcher) handleScheduleChange(ctx context.Context, scheduleType string,
 		return
 	}
 
-	// Get current power state to decide if we should notify
+	if w.isScheduleChangeDebounced() {
+		return
+	}
+
+	w.checkScheduleChange(ctx, scheduleType)
+}
+
+// handleCalendarChange processes calendar entity changes (event started, ended or edited)
+func (w *Watcher) handleCalendarChange(ctx context.Context, oldState, newState *homeassistant.Entity) {
+	if newState == nil {
+		return
+	}
+
+	if w.isScheduleChangeDebounced() {
+		return
+	}
+
+	w.checkCalendarSchedule(ctx)
+}
+
+// pollCalendar periodically re-reads the calendar, since edits to future events
+// don't change the calendar entity state
+func (w *Watcher) pollCalendar(ctx context.Context) {
+	interval := time.Duration(w.config.PollingInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.checkCalendarSchedule(ctx)
+		}
+	}
+}
+
+// usesCalendarSchedule reports whether any schedule time is derived from the calendar
+func (w *Watcher) usesCalendarSchedule() bool {
+	return w.config.IsCalendarScheduleEnabled() &&
+		(w.config.NextOnSensorID == "" || w.config.NextOffSensorID == "")
+}
+
+// isScheduleChangeDebounced checks if a schedule change happened too recently (avoid spam on reconnect)
+func (w *Watcher) isScheduleChangeDebounced() bool {
 	w.mu.Lock()
-	currentPowerState := w.lastState
 	timeSinceLastScheduleChange := time.Since(w.lastScheduleChange)
 	w.mu.Unlock()
 
-	// Debounce schedule changes (avoid spam on reconnect)
 	if timeSinceLastScheduleChange < w.debounceTime {
 		logger.Debug("Debouncing schedule change")
-		return
+		return true
 	}
+	return false
+}
 
-	// Parse new time
-	var sensorID string
-	if scheduleType == "on" {
-		sensorID = w.config.NextOnSensorID
-	} else {
-		sensorID = w.config.NextOffSensorID
+// checkScheduleChange re-reads the scheduled time and notifies if it changed
+func (w *Watcher) checkScheduleChange(ctx context.Context, scheduleType string) {
+	newTime, err := w.notifSvc.GetNextScheduledTime(ctx, scheduleType)
+	if err != nil {
+		logger.Warn("Failed to parse schedule time: %v", err)
+		return
 	}
 
-	newTime, err := w.notifSvc.GetScheduledTime(ctx, sensorID)
+	w.applyScheduleTime(ctx, scheduleType, newTime)
+}
+
+// checkCalendarSchedule reads the calendar once and checks the schedule times
+// derived from it; times read from next on/off sensors have their own handlers
+func (w *Watcher) checkCalendarSchedule(ctx context.Context) {
+	nextOff, nextOn, err := w.notifSvc.GetCalendarSchedule(ctx)
 	if err != nil {
-		logger.Warn("Failed to parse schedule time: %v", err)
+		logger.Warn("Failed to read calendar schedule: %v", err)
 		return
 	}
 
+	if w.config.NextOnSensorID == "" {
+		w.applyScheduleTime(ctx, "on", nextOn)
+	}
+	if w.config.NextOffSensorID == "" {
+		w.applyScheduleTime(ctx, "off", nextOff)
+	}
+}
+
+// applyScheduleTime stores a newly read scheduled time and notifies if it changed
+func (w *Watcher) applyScheduleTime(ctx context.Context, scheduleType string, newTime *time.Time) {
+	// Get current power state to decide if we should notify
+	w.mu.Lock()
+	currentPowerState := w.lastState
+	w.mu.Unlock()
+
 	// Get previous time
 	w.mu.Lock()
 	var oldTime *time.Time
@@ -260,6 +550,8 @@ func (w *Watcher) handleScheduleChange(ctx context.Context, scheduleType string,
 	w.lastScheduleChange = time.Now()
 	w.mu.Unlock()
 
+	w.notifSvc.FireScheduleChanged(ctx, string(currentPowerState), scheduleType, oldTime, newTime)
+
 	// Only notify about relevant schedule changes based on current power state
 	// When power is OFF - notify about next ON time changes
 	// When power is ON - notify about next OFF time changes
@@ -2,11 +2,18 @@ package watcher
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/yourusername/haaddon/telegram-bot/internal/config"
 	"github.com/yourusername/haaddon/telegram-bot/internal/homeassistant"
+	"github.com/yourusername/haaddon/telegram-bot/internal/notifications"
 )
 
 // testableWatcher wraps Watcher to track notification calls
@@ -480,3 +487,167 @@ func TestGetCurrentState(t *testing.T) {
 		t.Errorf("State should be %v, got %v", PowerStateOn, tw.GetCurrentState())
 	}
 }
+
+func TestStatus(t *testing.T) {
+	tw := createTestableWatcher()
+
+	if status := tw.Status(); status.State != PowerStateUnknown || !status.Since.IsZero() {
+		t.Errorf("Initial status = %+v, want unknown without since", status)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	tw.mu.Lock()
+	tw.lastState = PowerStateOff
+	tw.stateSince = since
+	tw.inferredOff = true
+	tw.mu.Unlock()
+
+	status := tw.Status()
+	if status.State != PowerStateOff || !status.Since.Equal(since) || !status.Inferred {
+		t.Errorf("Status() = %+v, want inferred off since %v", status, since)
+	}
+
+	// Without a journal nothing is counted
+	if off := tw.TotalOff(since, time.Now()); off != 0 {
+		t.Errorf("TotalOff() = %v, want 0", off)
+	}
+}
+
+func TestOutagesFromHistory(t *testing.T) {
+	states := []homeassistant.Entity{
+		{State: "on", LastChanged: "2026-01-04T08:00:00+00:00"},
+		{State: "off", LastChanged: "2026-01-04T10:00:00+00:00"},
+		{State: "unavailable", LastChanged: "2026-01-04T10:30:00+00:00"},
+		{State: "off", LastChanged: "2026-01-04T11:00:00+00:00"},
+		{State: "on", LastChanged: "2026-01-04T12:00:00.123456+00:00"},
+		{State: "off", LastChanged: "invalid"},
+		{State: "off", LastChanged: "2026-01-04T20:00:00+00:00"},
+	}
+
+	got := outagesFromHistory(states)
+
+	if len(got) != 2 {
+		t.Fatalf("outagesFromHistory() returned %d outages, want 2", len(got))
+	}
+
+	wantStart := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
+	if !got[0].Start.Equal(wantStart) {
+		t.Errorf("First outage start = %v, want %v", got[0].Start, wantStart)
+	}
+	if got[0].End == nil || got[0].Duration(time.Time{}).Truncate(time.Minute) != 2*time.Hour {
+		t.Errorf("First outage duration = %v, want 2h", got[0].Duration(time.Time{}))
+	}
+
+	if !got[1].Ongoing() {
+		t.Error("Last outage should be ongoing")
+	}
+}
+
+func createInferringWatcher() *Watcher {
+	return &Watcher{
+		config:       &config.Config{InferPowerFromConnection: true},
+		wsClient:     homeassistant.NewWSClient("http://localhost:8123", "token"),
+		lastState:    PowerStateUnknown,
+		debounceTime: 5 * time.Second,
+		connOffAfter: 20 * time.Millisecond,
+		connOnAfter:  20 * time.Millisecond,
+	}
+}
+
+func TestInferUntilConnected_NeverConnected(t *testing.T) {
+	w := createInferringWatcher()
+	started := time.Now()
+
+	// Home Assistant unreachable since startup: no Disconnected event ever arrives
+	w.inferUntilConnected(context.Background())
+	time.Sleep(100 * time.Millisecond)
+
+	status := w.Status()
+	if status.State != PowerStateOff || !status.Inferred {
+		t.Fatalf("Status() = %+v, want inferred off", status)
+	}
+	if status.Since.Before(started) || status.Since.After(started.Add(10*time.Millisecond)) {
+		t.Errorf("Since = %v, want startup time %v", status.Since, started)
+	}
+}
+
+func TestInferUntilConnected_Connected(t *testing.T) {
+	w := createInferringWatcher()
+	ctx := context.Background()
+
+	w.inferUntilConnected(ctx)
+	w.inferPowerState(ctx, homeassistant.ConnectionEvent{Type: homeassistant.ConnEventConnected, At: time.Now()})
+
+	w.mu.Lock()
+	pending := w.inferTimer != nil
+	w.mu.Unlock()
+	if pending {
+		t.Error("Off timer should be stopped by the first connection")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if state := w.GetCurrentState(); state != PowerStateOn {
+		t.Errorf("GetCurrentState() = %v, want %v", state, PowerStateOn)
+	}
+}
+
+func TestCheckCalendarSchedule(t *testing.T) {
+	offAt := time.Now().Add(2 * time.Hour).Truncate(time.Minute)
+	onAt := offAt.Add(3 * time.Hour)
+
+	var calendarCalls, stateCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/states/") {
+			stateCalls.Add(1)
+		}
+		if r.URL.Path != "/calendars/calendar.outages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calendarCalls.Add(1)
+		json.NewEncoder(w).Encode([]homeassistant.CalendarEvent{{
+			Summary: "Outage",
+			Start:   homeassistant.CalendarEventTime{DateTime: offAt.Format(time.RFC3339)},
+			End:     homeassistant.CalendarEventTime{DateTime: onAt.Format(time.RFC3339)},
+		}})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		nextOnID  string
+		wantOn    bool
+		wantOff   bool
+		wantCalls int32
+	}{
+		{name: "both from calendar", wantOn: true, wantOff: true, wantCalls: 1},
+		{name: "next on from sensor", nextOnID: "sensor.next_on", wantOn: false, wantOff: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendarCalls.Store(0)
+			stateCalls.Store(0)
+
+			cfg := &config.Config{CalendarEntityID: "calendar.outages", NextOnSensorID: tt.nextOnID, Timezone: "UTC"}
+			notifSvc, _ := notifications.NewService(nil, cfg, homeassistant.NewClient(server.URL, "token"), nil)
+			w := &Watcher{config: cfg, notifSvc: notifSvc, lastState: PowerStateUnknown}
+
+			w.checkCalendarSchedule(context.Background())
+
+			if got := calendarCalls.Load(); got != tt.wantCalls {
+				t.Errorf("calendar requests = %d, want %d", got, tt.wantCalls)
+			}
+			if got := stateCalls.Load(); got != 0 {
+				t.Errorf("sensor requests = %d, want 0", got)
+			}
+			if got := w.lastNextOffTime != nil && w.lastNextOffTime.Equal(offAt); got != tt.wantOff {
+				t.Errorf("next off = %v, want %v set: %v", formatTimePtr(w.lastNextOffTime), offAt.Format("15:04"), tt.wantOff)
+			}
+			if got := w.lastNextOnTime != nil && w.lastNextOnTime.Equal(onAt); got != tt.wantOn {
+				t.Errorf("next on = %v, want %v set: %v", formatTimePtr(w.lastNextOnTime), onAt.Format("15:04"), tt.wantOn)
+			}
+		})
+	}
+}